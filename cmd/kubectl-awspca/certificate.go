@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func newGetCertificateCommand(o *options) *cobra.Command {
+	var (
+		arnOnly bool
+		chain   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "get-certificate CERTIFICATEREQUEST",
+		Short: "Fetch the certificate issued for a CertificateRequest from PCA",
+		Long: `Look up the PCA certificate ARN recorded on a CertificateRequest and fetch
+the PEM encoded certificate from AWS Private CA.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cr, issuer, err := o.getCertificateRequest(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			annotations := cr.GetAnnotations()
+			certArn, ok := annotations[awspca.CertificateArnAnnotation]
+			if !ok {
+				return fmt.Errorf("CertificateRequest %s/%s has not been signed by PCA", cr.Namespace, cr.Name)
+			}
+			if arnOnly {
+				fmt.Fprintln(cmd.OutOrStdout(), certArn)
+				return nil
+			}
+
+			p, err := o.provisioner(ctx, issuer, annotations[awspca.CertificateAuthorityArnAnnotation])
+			if err != nil {
+				return err
+			}
+			certPem, chainPem, err := p.GetCertificate(ctx, certArn)
+			if err != nil {
+				return fmt.Errorf("failed to get certificate %s: %w", certArn, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s", certPem)
+			if chain {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s", chainPem)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&arnOnly, "arn", false, "Only print the PCA certificate ARN")
+	flags.BoolVar(&chain, "chain", false, "Print the certificate chain after the certificate")
+	return cmd
+}
+
+// getCertificateRequest returns the CertificateRequest in the namespace and
+// the issuer it refers to
+func (o *options) getCertificateRequest(ctx context.Context, name string) (*cmapi.CertificateRequest, api.GenericIssuer, error) {
+	cr, err := o.cm.CertmanagerV1().CertificateRequests(o.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	issuer, err := o.getIssuerForRef(ctx, cr.Namespace, cr.Spec.IssuerRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cr, issuer, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+)
+
+// testIssuers are the issuers referenced by the CertificateRequests and
+// Secrets of the command tests
+var testIssuers = []client.Object{
+	&api.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "issuer1"},
+		Spec:       api.AWSPCAIssuerSpec{Arn: "arn:issuer1"},
+	},
+	&api.AWSPCAClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "clusterissuer1"},
+		Spec:       api.AWSPCAIssuerSpec{Arn: "arn:clusterissuer1"},
+	},
+}
+
+// testCertificateRequestFor returns a CertificateRequest in ns1 referring to
+// an issuer with the annotations
+func testCertificateRequestFor(name string, ref cmmeta.ObjectReference, annotations map[string]string, cert []byte) *cmapi.CertificateRequest {
+	return &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name, Annotations: annotations},
+		Spec:       cmapi.CertificateRequestSpec{IssuerRef: ref},
+		Status:     cmapi.CertificateRequestStatus{Certificate: cert},
+	}
+}
+
+func TestGetCertificateCommand(t *testing.T) {
+	issuerRef := cmmeta.ObjectReference{Group: api.GroupVersion.Group, Kind: util.IssuerKind, Name: "issuer1"}
+	clusterIssuerRef := cmmeta.ObjectReference{Group: api.GroupVersion.Group, Kind: util.ClusterIssuerKind, Name: "clusterissuer1"}
+	signed := map[string]string{awspca.CertificateArnAnnotation: "arn:cert1"}
+
+	crs := []runtime.Object{
+		testCertificateRequestFor("cr-issuer", issuerRef, signed, nil),
+		testCertificateRequestFor("cr-cluster-issuer", clusterIssuerRef, map[string]string{
+			awspca.CertificateArnAnnotation:          "arn:cert2",
+			awspca.CertificateAuthorityArnAnnotation: "arn:previous-ca",
+		}, nil),
+		testCertificateRequestFor("cr-unsigned", issuerRef, nil, nil),
+		testCertificateRequestFor("cr-unknown-kind", cmmeta.ObjectReference{Group: api.GroupVersion.Group, Kind: "Issuer", Name: "issuer1"}, signed, nil),
+		testCertificateRequestFor("cr-other-group", cmmeta.ObjectReference{Group: "cert-manager.io", Kind: "Issuer", Name: "issuer1"}, signed, nil),
+	}
+
+	type testCase struct {
+		args            []string
+		expectedOutput  string
+		expectedCertArn string
+		expectedCAArn   string
+		expectError     func(error) bool
+	}
+
+	tests := map[string]testCase{
+		"certificate": {
+			args:            []string{"cr-issuer"},
+			expectedOutput:  "cert",
+			expectedCertArn: "arn:cert1",
+			expectedCAArn:   "arn:issuer1",
+		},
+		"certificate-and-chain": {
+			args:            []string{"cr-issuer", "--chain"},
+			expectedOutput:  "certchain",
+			expectedCertArn: "arn:cert1",
+			expectedCAArn:   "arn:issuer1",
+		},
+		"arn-only": {
+			args:           []string{"cr-issuer", "--arn"},
+			expectedOutput: "arn:cert1\n",
+		},
+		"cluster-issuer-with-recorded-ca": {
+			args:            []string{"cr-cluster-issuer"},
+			expectedOutput:  "cert",
+			expectedCertArn: "arn:cert2",
+			expectedCAArn:   "arn:previous-ca",
+		},
+		"not-signed": {
+			args:        []string{"cr-unsigned"},
+			expectError: func(err error) bool { return assert.ErrorContains(t, err, "has not been signed by PCA") },
+		},
+		"unknown-issuer-kind": {
+			args:        []string{"cr-unknown-kind"},
+			expectError: func(err error) bool { return errors.Is(err, util.ErrUnknownIssuerKind) },
+		},
+		"other-issuer-group": {
+			args:        []string{"cr-other-group"},
+			expectError: func(err error) bool { return assert.ErrorContains(t, err, "is not an AWS Private CA issuer") },
+		},
+		"certificate-request-not-found": {
+			args:        []string{"cr-missing"},
+			expectError: apierrors.IsNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &fakeProvisioner{cert: []byte("cert"), chain: []byte("chain")}
+			o := newTestOptions(t, p, testIssuers, crs, nil)
+
+			out, err := runCommand(t, newGetCertificateCommand(o), tc.args...)
+			if tc.expectError != nil {
+				assert.True(t, tc.expectError(err), "unexpected error: %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, out)
+			assert.Equal(t, tc.expectedCertArn, p.certArn)
+			if tc.expectedCAArn != "" && assert.NotNil(t, p.spec) {
+				assert.Equal(t, tc.expectedCAArn, p.spec.Arn)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-awspca is a kubectl plugin for inspecting and operating
+// AWSPCAIssuers and AWSPCAClusterIssuers.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(api.AddToScheme(scheme))
+}
+
+// provisioner is the part of awspca.PCAProvisioner used by the commands
+type provisioner interface {
+	awspca.GenericProvisioner
+	GetCertificate(ctx context.Context, certArn string) ([]byte, []byte, error)
+	RevokeCertificate(ctx context.Context, serial string, reason acmpcatypes.RevocationReason) error
+}
+
+// options holds the flags and clients shared by all commands
+type options struct {
+	loadingRules      *clientcmd.ClientConfigLoadingRules
+	overrides         *clientcmd.ConfigOverrides
+	issuerCredentials bool
+
+	namespace string
+	client    client.Client
+	cm        cmclient.Interface
+	kube      kubernetes.Interface
+
+	// newProvisioner creates the provisioner for an issuer spec, it is
+	// replaced in tests
+	newProvisioner func(ctx context.Context, client client.Client, spec *api.AWSPCAIssuerSpec) (provisioner, error)
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	o := &options{
+		loadingRules: clientcmd.NewDefaultClientConfigLoadingRules(),
+		overrides:    &clientcmd.ConfigOverrides{},
+		newProvisioner: func(ctx context.Context, client client.Client, spec *api.AWSPCAIssuerSpec) (provisioner, error) {
+			return awspca.NewProvisioner(ctx, client, spec)
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "kubectl-awspca",
+		Short: "Inspect and operate AWS Private CA issuers",
+		Long: `Inspect and operate AWSPCAIssuers and AWSPCAClusterIssuers.
+
+AWS API calls are made with the local AWS credentials, in the region of the
+issuer and assuming the role of the issuer if it has one. Use
+--issuer-credentials to use the credentials Secret of the issuer instead.`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return o.complete()
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&o.loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file to use")
+	clientcmd.BindOverrideFlags(o.overrides, flags, clientcmd.RecommendedConfigOverrideFlags(""))
+	flags.BoolVar(&o.issuerCredentials, "issuer-credentials", false,
+		"Use the AWS credentials Secret referenced by the issuer instead of the local AWS credentials")
+
+	cmd.AddCommand(
+		newStatusCommand(o),
+		newTestSignCommand(o),
+		newGetCertificateCommand(o),
+		newRevokeCommand(o),
+	)
+	return cmd
+}
+
+// complete creates the clients from the kubeconfig
+func (o *options) complete() error {
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(o.loadingRules, o.overrides)
+
+	var err error
+	if o.namespace, _, err = config.Namespace(); err != nil {
+		return err
+	}
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return err
+	}
+	if o.client, err = client.New(restConfig, client.Options{Scheme: scheme}); err != nil {
+		return err
+	}
+	if o.cm, err = cmclient.NewForConfig(restConfig); err != nil {
+		return err
+	}
+	if o.kube, err = kubernetes.NewForConfig(restConfig); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getIssuerForRef returns the issuer referenced by a CertificateRequest or
+// Secret in the namespace
+func (o *options) getIssuerForRef(ctx context.Context, namespace string, ref cmmeta.ObjectReference) (api.GenericIssuer, error) {
+	if ref.Group != api.GroupVersion.Group {
+		return nil, fmt.Errorf("issuer %s of group %q is not an AWS Private CA issuer", ref.Name, ref.Group)
+	}
+	return o.getIssuer(ctx, ref.Kind, namespace, ref.Name)
+}
+
+// getIssuer returns the issuer of the given kind, util.IssuerKind or
+// util.ClusterIssuerKind
+func (o *options) getIssuer(ctx context.Context, kind, namespace, name string) (api.GenericIssuer, error) {
+	return util.GetIssuer(ctx, o.client, kind, types.NamespacedName{Namespace: namespace, Name: name})
+}
+
+// provisioner returns a provisioner for the CA of the issuer, or for the CA
+// with the given ARN using the credentials and region of the issuer
+func (o *options) provisioner(ctx context.Context, issuer api.GenericIssuer, caArn string) (provisioner, error) {
+	spec := issuer.GetSpec().DeepCopy()
+	if caArn != "" {
+		spec.Arn = caArn
+	}
+
+	c := o.client
+	if !o.issuerCredentials {
+		c = nil
+		spec.SecretRef = api.AWSCredentialsSecretReference{}
+	}
+
+	p, err := o.newProvisioner(ctx, c, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS client for issuer %s: %w", issuer.GetName(), err)
+	}
+	return p, nil
+}
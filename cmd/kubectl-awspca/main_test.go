@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+// fakeProvisioner records the calls made by the commands
+type fakeProvisioner struct {
+	spec        *api.AWSPCAIssuerSpec
+	ca          *awspca.CertificateAuthority
+	caBundle    []byte
+	cert        []byte
+	chain       []byte
+	getErr      error
+	certArn     string
+	revoked     string
+	reason      acmpcatypes.RevocationReason
+	revokeErr   error
+	describeErr error
+}
+
+func (p *fakeProvisioner) Get(ctx context.Context, cr *cmapi.CertificateRequest, certArn string, log logr.Logger) ([]byte, []byte, error) {
+	return p.GetCertificate(ctx, certArn)
+}
+
+func (p *fakeProvisioner) Sign(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string, log logr.Logger) error {
+	return nil
+}
+
+func (p *fakeProvisioner) DryRun(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string, log logr.Logger) error {
+	return nil
+}
+
+func (p *fakeProvisioner) GetCACertificate(ctx context.Context) ([]byte, error) {
+	return p.caBundle, nil
+}
+
+func (p *fakeProvisioner) DescribeCertificateAuthority(ctx context.Context) (*awspca.CertificateAuthority, error) {
+	return p.ca, p.describeErr
+}
+
+func (p *fakeProvisioner) GetCertificate(ctx context.Context, certArn string) ([]byte, []byte, error) {
+	p.certArn = certArn
+	return p.cert, p.chain, p.getErr
+}
+
+func (p *fakeProvisioner) RevokeCertificate(ctx context.Context, serial string, reason acmpcatypes.RevocationReason) error {
+	p.revoked, p.reason = serial, reason
+	return p.revokeErr
+}
+
+// newTestOptions returns options with fake clients holding the objects and a
+// provisioner factory returning p
+func newTestOptions(t *testing.T, p *fakeProvisioner, issuers []client.Object, cmObjects []runtime.Object, kubeObjects []runtime.Object) *options {
+	t.Helper()
+	return &options{
+		namespace: "ns1",
+		client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(issuers...).Build(),
+		cm:        cmfake.NewSimpleClientset(cmObjects...),
+		kube:      kubefake.NewSimpleClientset(kubeObjects...),
+		newProvisioner: func(ctx context.Context, client client.Client, spec *api.AWSPCAIssuerSpec) (provisioner, error) {
+			p.spec = spec
+			return p, nil
+		},
+	}
+}
+
+// runCommand executes cmd with args and returns its output
+func runCommand(t *testing.T, cmd *cobra.Command, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd.SetArgs(args)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage = true
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+// testCertificate returns a PEM encoded self-signed certificate with the
+// serial number
+func testCertificate(t *testing.T, serial int64) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: "test"}}, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
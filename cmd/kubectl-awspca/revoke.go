@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func newRevokeCommand(o *options) *cobra.Command {
+	var (
+		crName     string
+		secretName string
+		reason     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "revoke (--certificate-request NAME | --secret NAME)",
+		Short: "Revoke a certificate issued by PCA",
+		Long: `Revoke the certificate issued for a CertificateRequest, or stored in a
+Secret by cert-manager, with AWS Private CA.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			revocationReason := acmpcatypes.RevocationReason(reason)
+			if !slices.Contains(revocationReason.Values(), revocationReason) {
+				return fmt.Errorf("invalid revocation reason %q, must be one of %v", reason, revocationReason.Values())
+			}
+
+			var (
+				target string
+				serial string
+				p      provisioner
+				err    error
+			)
+			switch {
+			case crName != "" && secretName == "":
+				target = "CertificateRequest " + o.namespace + "/" + crName
+				p, serial, err = o.certificateRequestSerial(ctx, crName)
+			case secretName != "" && crName == "":
+				target = "Secret " + o.namespace + "/" + secretName
+				p, serial, err = o.secretSerial(ctx, secretName)
+			default:
+				return fmt.Errorf("exactly one of --certificate-request and --secret is required")
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := p.RevokeCertificate(ctx, serial, revocationReason); err != nil {
+				return fmt.Errorf("failed to revoke certificate %s: %w", serial, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Revoked certificate %s of %s\n", serial, target)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&crName, "certificate-request", "", "Name of the CertificateRequest the certificate was issued for")
+	flags.StringVar(&secretName, "secret", "", "Name of the Secret holding the certificate")
+	flags.StringVar(&reason, "reason", string(acmpcatypes.RevocationReasonUnspecified), "Reason for the revocation")
+	return cmd
+}
+
+// certificateRequestSerial returns the serial number of the certificate
+// issued for a CertificateRequest and a provisioner for the CA that issued it
+func (o *options) certificateRequestSerial(ctx context.Context, name string) (provisioner, string, error) {
+	cr, issuer, err := o.getCertificateRequest(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	annotations := cr.GetAnnotations()
+	serial, ok := annotations[awspca.SerialNumberAnnotation]
+	if !ok {
+		if len(cr.Status.Certificate) == 0 {
+			return nil, "", fmt.Errorf("CertificateRequest %s/%s has no certificate", cr.Namespace, cr.Name)
+		}
+		if serial, err = awspca.CertificateSerialNumber(cr.Status.Certificate); err != nil {
+			return nil, "", err
+		}
+	}
+
+	p, err := o.provisioner(ctx, issuer, annotations[awspca.CertificateAuthorityArnAnnotation])
+	if err != nil {
+		return nil, "", err
+	}
+	return p, serial, nil
+}
+
+// secretSerial returns the serial number of the certificate in a Secret
+// managed by cert-manager and a provisioner for the issuer that issued it
+func (o *options) secretSerial(ctx context.Context, name string) (provisioner, string, error) {
+	secret, err := o.kube.CoreV1().Secrets(o.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	serial, err := awspca.CertificateSerialNumber(secret.Data[core.TLSCertKey])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read certificate of Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	annotations := secret.GetAnnotations()
+	issuerName, ok := annotations[cmapi.IssuerNameAnnotationKey]
+	if !ok {
+		return nil, "", fmt.Errorf("secret %s/%s has no %s annotation", secret.Namespace, secret.Name, cmapi.IssuerNameAnnotationKey)
+	}
+	issuer, err := o.getIssuerForRef(ctx, secret.Namespace, cmmeta.ObjectReference{
+		Name:  issuerName,
+		Kind:  annotations[cmapi.IssuerKindAnnotationKey],
+		Group: annotations[cmapi.IssuerGroupAnnotationKey],
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	p, err := o.provisioner(ctx, issuer, "")
+	if err != nil {
+		return nil, "", err
+	}
+	return p, serial, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+)
+
+func TestRevokeCommand(t *testing.T) {
+	cert := testCertificate(t, 0x1234)
+	certSerial, err := awspca.CertificateSerialNumber(cert)
+	require.NoError(t, err)
+
+	issuerRef := cmmeta.ObjectReference{Group: api.GroupVersion.Group, Kind: util.IssuerKind, Name: "issuer1"}
+	crs := []runtime.Object{
+		testCertificateRequestFor("cr-annotated", issuerRef, map[string]string{
+			awspca.SerialNumberAnnotation:            "aa:bb",
+			awspca.CertificateAuthorityArnAnnotation: "arn:previous-ca",
+		}, nil),
+		testCertificateRequestFor("cr-certificate", issuerRef, nil, cert),
+		testCertificateRequestFor("cr-pending", issuerRef, nil, nil),
+		testCertificateRequestFor("cr-unknown-kind", cmmeta.ObjectReference{Group: api.GroupVersion.Group, Kind: "Issuer", Name: "issuer1"}, nil, cert),
+	}
+
+	secret := func(name string, annotations map[string]string) *core.Secret {
+		return &core.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name, Annotations: annotations},
+			Data:       map[string][]byte{core.TLSCertKey: cert},
+		}
+	}
+	secrets := []runtime.Object{
+		secret("secret-issuer", map[string]string{
+			cmapi.IssuerNameAnnotationKey:  "issuer1",
+			cmapi.IssuerKindAnnotationKey:  util.IssuerKind,
+			cmapi.IssuerGroupAnnotationKey: api.GroupVersion.Group,
+		}),
+		secret("secret-cluster-issuer", map[string]string{
+			cmapi.IssuerNameAnnotationKey:  "clusterissuer1",
+			cmapi.IssuerKindAnnotationKey:  util.ClusterIssuerKind,
+			cmapi.IssuerGroupAnnotationKey: api.GroupVersion.Group,
+		}),
+		secret("secret-no-kind", map[string]string{
+			cmapi.IssuerNameAnnotationKey:  "issuer1",
+			cmapi.IssuerGroupAnnotationKey: api.GroupVersion.Group,
+		}),
+		secret("secret-no-issuer", nil),
+	}
+
+	type testCase struct {
+		args           []string
+		revokeErr      error
+		expectedSerial string
+		expectedReason acmpcatypes.RevocationReason
+		expectedCAArn  string
+		expectError    func(error) bool
+	}
+
+	tests := map[string]testCase{
+		"certificate-request-serial-annotation": {
+			args:           []string{"--certificate-request", "cr-annotated"},
+			expectedSerial: "aa:bb",
+			expectedReason: acmpcatypes.RevocationReasonUnspecified,
+			expectedCAArn:  "arn:previous-ca",
+		},
+		"certificate-request-certificate": {
+			args:           []string{"--certificate-request", "cr-certificate", "--reason", "KEY_COMPROMISE"},
+			expectedSerial: certSerial,
+			expectedReason: acmpcatypes.RevocationReasonKeyCompromise,
+			expectedCAArn:  "arn:issuer1",
+		},
+		"certificate-request-without-certificate": {
+			args:        []string{"--certificate-request", "cr-pending"},
+			expectError: func(err error) bool { return assert.ErrorContains(t, err, "has no certificate") },
+		},
+		"certificate-request-unknown-issuer-kind": {
+			args:        []string{"--certificate-request", "cr-unknown-kind"},
+			expectError: func(err error) bool { return errors.Is(err, util.ErrUnknownIssuerKind) },
+		},
+		"secret-issuer": {
+			args:           []string{"--secret", "secret-issuer"},
+			expectedSerial: certSerial,
+			expectedReason: acmpcatypes.RevocationReasonUnspecified,
+			expectedCAArn:  "arn:issuer1",
+		},
+		"secret-cluster-issuer": {
+			args:           []string{"--secret", "secret-cluster-issuer", "--reason", "SUPERSEDED"},
+			expectedSerial: certSerial,
+			expectedReason: acmpcatypes.RevocationReasonSuperseded,
+			expectedCAArn:  "arn:clusterissuer1",
+		},
+		"secret-without-issuer-kind": {
+			args:        []string{"--secret", "secret-no-kind"},
+			expectError: func(err error) bool { return errors.Is(err, util.ErrUnknownIssuerKind) },
+		},
+		"secret-without-issuer": {
+			args:        []string{"--secret", "secret-no-issuer"},
+			expectError: func(err error) bool { return assert.ErrorContains(t, err, cmapi.IssuerNameAnnotationKey) },
+		},
+		"secret-not-found": {
+			args:        []string{"--secret", "secret-missing"},
+			expectError: apierrors.IsNotFound,
+		},
+		"both-targets": {
+			args:        []string{"--secret", "secret-issuer", "--certificate-request", "cr-certificate"},
+			expectError: func(err error) bool { return assert.ErrorContains(t, err, "exactly one of") },
+		},
+		"invalid-reason": {
+			args:        []string{"--secret", "secret-issuer", "--reason", "BORED"},
+			expectError: func(err error) bool { return assert.ErrorContains(t, err, "invalid revocation reason") },
+		},
+		"revoke-error": {
+			args:        []string{"--secret", "secret-issuer"},
+			revokeErr:   errors.New("access denied"),
+			expectError: func(err error) bool { return assert.ErrorContains(t, err, "access denied") },
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &fakeProvisioner{revokeErr: tc.revokeErr}
+			o := newTestOptions(t, p, testIssuers, crs, secrets)
+
+			out, err := runCommand(t, newRevokeCommand(o), tc.args...)
+			if tc.expectError != nil {
+				assert.True(t, tc.expectError(err), "unexpected error: %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSerial, p.revoked)
+			assert.Equal(t, tc.expectedReason, p.reason)
+			assert.Contains(t, out, "Revoked certificate "+tc.expectedSerial)
+			if assert.NotNil(t, p.spec) {
+				assert.Equal(t, tc.expectedCAArn, p.spec.Arn)
+				assert.Empty(t, p.spec.SecretRef, "the local AWS credentials are used by default")
+			}
+		})
+	}
+}
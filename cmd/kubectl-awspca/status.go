@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+)
+
+func newStatusCommand(o *options) *cobra.Command {
+	var cluster bool
+
+	cmd := &cobra.Command{
+		Use:   "status [NAME]",
+		Short: "Show the status of issuers and the details of their CA",
+		Long: `Show the status of issuers.
+
+Without a name, the issuers of the namespace, or all cluster issuers with
+--cluster, are listed. With a name, the status of the issuer and the details
+of its CA, as described by AWS Private CA, are shown.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+
+			if len(args) == 0 {
+				return o.listIssuers(cmd, cluster)
+			}
+
+			kind := util.IssuerKind
+			if cluster {
+				kind = util.ClusterIssuerKind
+			}
+			issuer, err := o.getIssuer(ctx, kind, o.namespace, args[0])
+			if err != nil {
+				return err
+			}
+			printIssuer(out, issuer)
+
+			p, err := o.provisioner(ctx, issuer, "")
+			if err != nil {
+				return err
+			}
+			ca, err := p.DescribeCertificateAuthority(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to describe CA: %w", err)
+			}
+			bundle, err := p.GetCACertificate(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get CA certificate: %w", err)
+			}
+
+			w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "\nCA:\n")
+			fmt.Fprintf(w, "  Type:\t%s\n", ca.Type)
+			fmt.Fprintf(w, "  Status:\t%s\n", ca.Status)
+			fmt.Fprintf(w, "  Usage Mode:\t%s\n", ca.UsageMode)
+			fmt.Fprintf(w, "  Key Algorithm:\t%s\n", ca.KeyAlgorithm)
+			fmt.Fprintf(w, "  Signing Algorithm:\t%s\n", ca.SigningAlgorithm)
+			fmt.Fprintf(w, "  Not Before:\t%s\n", formatTime(ca.NotBefore))
+			fmt.Fprintf(w, "  Not After:\t%s\n", formatTime(ca.NotAfter))
+			fmt.Fprintf(w, "  CRL:\t%s\n", formatRevocation(ca.Revocation.CRLEnabled, ca.Revocation.CRLDistributionPoint))
+			fmt.Fprintf(w, "  OCSP:\t%s\n", formatRevocation(ca.Revocation.OCSPEnabled, ca.Revocation.OCSPURL))
+			if block, _ := pem.Decode(bundle); block != nil {
+				if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+					fmt.Fprintf(w, "  Subject:\t%s\n", cert.Subject)
+				}
+				sum := sha256.Sum256(block.Bytes)
+				fmt.Fprintf(w, "  Fingerprint:\t%s\n", hex.EncodeToString(sum[:]))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVar(&cluster, "cluster", false, "Show AWSPCAClusterIssuers instead of AWSPCAIssuers")
+	return cmd
+}
+
+func (o *options) listIssuers(cmd *cobra.Command, cluster bool) error {
+	ctx := cmd.Context()
+	var issuers []api.GenericIssuer
+	if cluster {
+		list := new(api.AWSPCAClusterIssuerList)
+		if err := o.client.List(ctx, list); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			issuers = append(issuers, &list.Items[i])
+		}
+	} else {
+		list := new(api.AWSPCAIssuerList)
+		if err := o.client.List(ctx, list, client.InNamespace(o.namespace)); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			issuers = append(issuers, &list.Items[i])
+		}
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREADY\tREASON\tARN")
+	for _, issuer := range issuers {
+		ready, reason := "Unknown", ""
+		if c := meta.FindStatusCondition(issuer.GetStatus().Conditions, api.ConditionTypeReady); c != nil {
+			ready, reason = string(c.Status), c.Reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issuer.GetName(), ready, reason, issuer.GetSpec().Arn)
+	}
+	return w.Flush()
+}
+
+func printIssuer(out io.Writer, issuer api.GenericIssuer) {
+	spec := issuer.GetSpec()
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", issuer.GetName())
+	if ns := issuer.GetNamespace(); ns != "" {
+		fmt.Fprintf(w, "Namespace:\t%s\n", ns)
+	}
+	fmt.Fprintf(w, "ARN:\t%s\n", spec.Arn)
+	fmt.Fprintf(w, "Region:\t%s\n", spec.Region)
+	if spec.Role != "" {
+		fmt.Fprintf(w, "Role:\t%s\n", spec.Role)
+	}
+	if fp := issuer.GetStatus().CACertificateFingerprint; fp != "" {
+		fmt.Fprintf(w, "Observed CA Fingerprint:\t%s\n", fp)
+	}
+	fmt.Fprintf(w, "Conditions:\n")
+	for _, c := range issuer.GetStatus().Conditions {
+		fmt.Fprintf(w, "  %s:\t%s\t%s\t%s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+	_ = w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatRevocation(enabled bool, url string) string {
+	switch {
+	case !enabled:
+		return "Disabled"
+	case url == "":
+		return "Enabled"
+	}
+	return url
+}
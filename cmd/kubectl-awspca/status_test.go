@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestStatusCommand(t *testing.T) {
+	issuers := []client.Object{
+		&api.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "issuer1"},
+			Spec:       api.AWSPCAIssuerSpec{Arn: "arn:issuer1", Region: "us-east-1"},
+			Status: api.AWSPCAIssuerStatus{Conditions: []metav1.Condition{
+				{Type: api.ConditionTypeReady, Status: metav1.ConditionTrue, Reason: "Verified"},
+			}},
+		},
+		&api.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "issuer2"},
+			Spec:       api.AWSPCAIssuerSpec{Arn: "arn:issuer2"},
+		},
+		&api.AWSPCAClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "clusterissuer1"},
+			Spec:       api.AWSPCAIssuerSpec{Arn: "arn:clusterissuer1", Region: "eu-west-1"},
+		},
+	}
+
+	type testCase struct {
+		args           []string
+		expectedOutput []string
+		unexpected     []string
+		expectedArn    string
+		expectError    func(error) bool
+	}
+
+	tests := map[string]testCase{
+		"list-issuers": {
+			expectedOutput: []string{"issuer1", "True", "Verified", "arn:issuer1"},
+			unexpected:     []string{"issuer2", "clusterissuer1"},
+		},
+		"list-cluster-issuers": {
+			args:           []string{"--cluster"},
+			expectedOutput: []string{"clusterissuer1", "Unknown", "arn:clusterissuer1"},
+			unexpected:     []string{"issuer2"},
+		},
+		"issuer": {
+			args:           []string{"issuer1"},
+			expectedOutput: []string{"Name:", "issuer1", "Namespace:", "ns1", "Type:", "ROOT", "Status:", "ACTIVE", "CRL:", "http://crl", "OCSP:", "Disabled"},
+			expectedArn:    "arn:issuer1",
+		},
+		"cluster-issuer": {
+			args:           []string{"--cluster", "clusterissuer1"},
+			expectedOutput: []string{"clusterissuer1", "eu-west-1"},
+			unexpected:     []string{"Namespace:"},
+			expectedArn:    "arn:clusterissuer1",
+		},
+		"issuer-in-other-namespace": {
+			args:        []string{"issuer2"},
+			expectError: apierrors.IsNotFound,
+		},
+		"cluster-issuer-is-not-an-issuer": {
+			args:        []string{"clusterissuer1"},
+			expectError: apierrors.IsNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &fakeProvisioner{ca: &awspca.CertificateAuthority{
+				Type:       "ROOT",
+				Status:     "ACTIVE",
+				Revocation: api.RevocationStatus{CRLEnabled: true, CRLDistributionPoint: "http://crl"},
+			}}
+			o := newTestOptions(t, p, issuers, nil, nil)
+
+			out, err := runCommand(t, newStatusCommand(o), tc.args...)
+			if tc.expectError != nil {
+				assert.True(t, tc.expectError(err), "unexpected error: %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			for _, s := range tc.expectedOutput {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tc.unexpected {
+				assert.NotContains(t, out, s)
+			}
+			if tc.expectedArn != "" {
+				if assert.NotNil(t, p.spec) {
+					assert.Equal(t, tc.expectedArn, p.spec.Arn)
+				}
+			} else {
+				assert.Nil(t, p.spec, "no provisioner expected for a list")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+)
+
+// issueTimeout is how long to wait for PCA to issue a certificate
+const issueTimeout = 2 * time.Minute
+
+func newTestSignCommand(o *options) *cobra.Command {
+	var (
+		cluster    bool
+		commonName string
+		duration   time.Duration
+		template   string
+		printPEM   bool
+		revoke     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test-sign NAME",
+		Short: "Issue a throwaway certificate through an issuer",
+		Long: `Issue a certificate for a freshly generated key through an issuer, the way
+the controller would for a CertificateRequest, and verify the result.
+
+The certificate is really issued by AWS Private CA. Pass --revoke to revoke it
+once it has been verified.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kind := util.IssuerKind
+			if cluster {
+				kind = util.ClusterIssuerKind
+			}
+			issuer, err := o.getIssuer(ctx, kind, o.namespace, args[0])
+			if err != nil {
+				return err
+			}
+			p, err := o.provisioner(ctx, issuer, "")
+			if err != nil {
+				return err
+			}
+
+			cr, err := testCertificateRequest(o.namespace, commonName, duration)
+			if err != nil {
+				return err
+			}
+			if template == "" && issuer.GetSpec().PCATemplate != nil {
+				template = issuer.GetSpec().PCATemplate.DefaultTemplateName
+			}
+			if err := p.Sign(ctx, cr, template, logr.Discard()); err != nil {
+				return fmt.Errorf("failed to issue certificate: %w", err)
+			}
+			certArn := cr.GetAnnotations()[awspca.CertificateArnAnnotation]
+
+			var certPem []byte
+			err = wait.PollUntilContextTimeout(ctx, 2*time.Second, issueTimeout, true, func(ctx context.Context) (bool, error) {
+				certPem, _, err = p.Get(ctx, cr, certArn, logr.Discard())
+				var inProgress *acmpcatypes.RequestInProgressException
+				if errors.As(err, &inProgress) {
+					return false, nil
+				}
+				return err == nil, err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to get certificate %s: %w", certArn, err)
+			}
+
+			annotations := cr.GetAnnotations()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "Certificate ARN:\t%s\n", certArn)
+			fmt.Fprintf(w, "Template ARN:\t%s\n", annotations[awspca.TemplateArnAnnotation])
+			fmt.Fprintf(w, "Signing Algorithm:\t%s\n", annotations[awspca.SigningAlgorithmAnnotation])
+			fmt.Fprintf(w, "Serial Number:\t%s\n", annotations[awspca.SerialNumberAnnotation])
+			fmt.Fprintf(w, "Not Before:\t%s\n", annotations[awspca.NotBeforeAnnotation])
+			fmt.Fprintf(w, "Not After:\t%s\n", annotations[awspca.NotAfterAnnotation])
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if printPEM {
+				fmt.Fprintf(cmd.OutOrStdout(), "\n%s", certPem)
+			}
+
+			if revoke {
+				err := p.RevokeCertificate(ctx, annotations[awspca.SerialNumberAnnotation], acmpcatypes.RevocationReasonCessationOfOperation)
+				if err != nil {
+					return fmt.Errorf("failed to revoke certificate %s: %w", certArn, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Revoked certificate %s\n", certArn)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&cluster, "cluster", false, "Use an AWSPCAClusterIssuer instead of an AWSPCAIssuer")
+	flags.StringVar(&commonName, "common-name", "kubectl-awspca-test.invalid", "Common name and DNS name of the certificate")
+	flags.DurationVar(&duration, "duration", time.Hour, "Validity of the certificate")
+	flags.StringVar(&template, "template", "", "PCA template name, defaults to the template the controller would use")
+	flags.BoolVar(&printPEM, "pem", false, "Print the PEM encoded certificate chain")
+	flags.BoolVar(&revoke, "revoke", false, "Revoke the certificate once it has been verified")
+	return cmd
+}
+
+// testCertificateRequest returns an in-memory CertificateRequest for a new
+// ECDSA key. Its name is random so that PCA does not treat it as a retry of
+// an earlier request.
+func testCertificateRequest(namespace, commonName string, duration time.Duration) (*cmapi.CertificateRequest, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: []string{commonName},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSR: %w", err)
+	}
+
+	return &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "kubectl-awspca-test-" + utilrand.String(8),
+		},
+		Spec: cmapi.CertificateRequestSpec{
+			Request:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+			Duration: &metav1.Duration{Duration: duration},
+			Usages:   []cmapi.KeyUsage{cmapi.UsageServerAuth},
+		},
+	}, nil
+}
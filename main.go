@@ -17,8 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 
@@ -26,18 +30,33 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	awspcacertmanageriov1beta1 "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/audit"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	issuerconfig "github.com/cert-manager/aws-privateca-issuer/pkg/config"
 	"github.com/cert-manager/aws-privateca-issuer/pkg/controllers"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/health"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/metrics"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/sharding"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/tracing"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -54,21 +73,91 @@ func init() {
 }
 
 func main() {
+	var configFile string
 	var metricsAddr string
 	var enableLeaderElection bool
+	var leaderElectionLeaseDuration time.Duration
+	var leaderElectionRenewDeadline time.Duration
+	var leaderElectionRetryPeriod time.Duration
+	var leaderElectionNamespace string
+	var leaderElectionResourceLock string
 	var probeAddr string
 	var disableApprovedCheck bool
 	var disableClientSideRateLimiting bool
+	var idempotencyTokenStrategy string
+	var requireCARevocation bool
+	var enableApprover bool
+	var auditLogPath string
+	var tracingOpts tracing.Options
+	var watchNamespaces string
+	var issuerLabelSelector string
+	var instanceName string
+	var checkDefaultIdentity bool
+	var issuerReadinessWindow time.Duration
+	var enableSharding bool
+	var shards int
+	var shardLeaseDuration time.Duration
+	var shardRenewPeriod time.Duration
+	var shardLeaseNamespace string
 
+	flag.StringVar(&configFile, "config", "",
+		"The path of a ControllerConfiguration file. Flags that are set explicitly take precedence over the file.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&leaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"How long non-leader replicas wait before acquiring leadership of a lease that was not renewed.")
+	flag.DurationVar(&leaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"How long the leader retries renewing its lease before giving up leadership.")
+	flag.DurationVar(&leaderElectionRetryPeriod, "leader-election-retry-period", 2*time.Second,
+		"How long replicas wait between attempts to acquire or renew the lease.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"The namespace the leader election lease is created in. Defaults to the namespace of the controller.")
+	flag.StringVar(&leaderElectionResourceLock, "leader-election-resource-lock", resourcelock.LeasesResourceLock,
+		"The kind of resource the leader election lease is held on. Only leases is supported.")
 	flag.BoolVar(&disableApprovedCheck, "disable-approved-check", false,
 		"Disables waiting for CertificateRequests to have an approved condition before signing.")
 	flag.BoolVar(&disableClientSideRateLimiting, "disable-client-side-rate-limiting", false,
 		"Disables Kubernetes client-side rate limiting (only use if API Priority & Fairness is enabled on the cluster).")
+	flag.StringVar(&idempotencyTokenStrategy, "idempotency-token-strategy", string(awspca.IdempotencyTokenStrategyRequest),
+		"What the idempotency token passed to PCA is derived from: Request (namespace, name, UID and CSR of the "+
+			"CertificateRequest) or Name (namespace and name only, as earlier releases did).")
+	flag.BoolVar(&requireCARevocation, "require-ca-revocation", false,
+		"Keep issuers whose CA has neither a CRL nor OCSP enabled from becoming Ready, regardless of their revocationCheck.")
+	flag.BoolVar(&enableApprover, "enable-approver", false,
+		"Run the built-in approver, which approves or denies the CertificateRequests of issuers with an approval policy.")
+	flag.StringVar(&auditLogPath, "audit-log-path", "",
+		"The path of a file JSON lines audit records of CertificateRequest decisions are appended to, or - for stdout. "+
+			"The audit log is disabled if empty.")
+	flag.StringVar(&tracingOpts.Endpoint, "tracing-endpoint", "",
+		"The host and port of an OTLP/HTTP collector traces are exported to. Tracing is disabled if empty.")
+	flag.BoolVar(&tracingOpts.Insecure, "tracing-insecure", false,
+		"Disables TLS when exporting traces to the collector.")
+	flag.Float64Var(&tracingOpts.SampleRatio, "tracing-sample-ratio", 1,
+		"The fraction of traces that are sampled.")
+	flag.BoolVar(&checkDefaultIdentity, "readiness-check-default-identity", false,
+		"Report unready while the default AWS identity of the controller cannot call sts.GetCallerIdentity.")
+	flag.DurationVar(&issuerReadinessWindow, "readiness-issuer-window", health.DefaultIssuerWindow,
+		"Report unready when all issuers have failed verification for this long. 0 disables the check.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"A comma-separated list of namespaces to watch. All namespaces are watched if empty.")
+	flag.StringVar(&issuerLabelSelector, "issuer-label-selector", "",
+		"A label selector restricting the issuers handled by this instance. All issuers are handled if empty.")
+	flag.StringVar(&instanceName, "instance-name", "",
+		"Only handle issuers whose aws-privateca-issuer/instance annotation has this value. "+
+			"If empty, only issuers without the annotation are handled.")
+	flag.BoolVar(&enableSharding, "enable-sharding", false,
+		"Sign CertificateRequests on every replica, each handling the shards it holds a lease for, instead of only on the leader.")
+	flag.IntVar(&shards, "shards", 16,
+		"The number of shards CertificateRequests are spread over when sharding is enabled. Must be the same on every replica.")
+	flag.DurationVar(&shardLeaseDuration, "shard-lease-duration", 15*time.Second,
+		"How long the shards of a replica that stopped renewing its leases wait before they are taken over.")
+	flag.DurationVar(&shardRenewPeriod, "shard-renew-period", 5*time.Second,
+		"How often replicas renew their shard leases and reassign the shards.")
+	flag.StringVar(&shardLeaseNamespace, "shard-lease-namespace", "",
+		"The namespace the shard leases are created in. Defaults to the namespace of the leader election lease.")
 
 	opts := zap.Options{
 		Development: false,
@@ -78,37 +167,232 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	cfg, err := issuerconfig.Load(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load config file")
+		os.Exit(1)
+	}
+	// overrideFromFlags applies the flags that are set explicitly, which take
+	// precedence over the config file
+	overrideFromFlags := func(c *issuerconfig.ControllerConfiguration) {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "metrics-bind-address":
+				c.Metrics.BindAddress = metricsAddr
+			case "health-probe-bind-address":
+				c.Health.BindAddress = probeAddr
+			case "leader-elect":
+				c.LeaderElection.Enabled = enableLeaderElection
+			case "leader-election-lease-duration":
+				c.LeaderElection.LeaseDuration = &metav1.Duration{Duration: leaderElectionLeaseDuration}
+			case "leader-election-renew-deadline":
+				c.LeaderElection.RenewDeadline = &metav1.Duration{Duration: leaderElectionRenewDeadline}
+			case "leader-election-retry-period":
+				c.LeaderElection.RetryPeriod = &metav1.Duration{Duration: leaderElectionRetryPeriod}
+			case "leader-election-namespace":
+				c.LeaderElection.Namespace = leaderElectionNamespace
+			case "leader-election-resource-lock":
+				c.LeaderElection.ResourceLock = leaderElectionResourceLock
+			case "disable-approved-check":
+				c.Controller.DisableApprovedCheck = disableApprovedCheck
+			case "disable-client-side-rate-limiting":
+				c.KubernetesClient.DisableRateLimiting = disableClientSideRateLimiting
+			case "idempotency-token-strategy":
+				c.Controller.IdempotencyTokenStrategy = idempotencyTokenStrategy
+			case "require-ca-revocation":
+				c.Controller.RequireCARevocation = requireCARevocation
+			case "enable-approver":
+				c.Controller.EnableApprover = enableApprover
+			case "audit-log-path":
+				c.Audit.Path = auditLogPath
+			case "tracing-endpoint":
+				c.Tracing.Endpoint = tracingOpts.Endpoint
+			case "tracing-insecure":
+				c.Tracing.Insecure = tracingOpts.Insecure
+			case "tracing-sample-ratio":
+				c.Tracing.SampleRatio = &tracingOpts.SampleRatio
+			case "readiness-check-default-identity":
+				c.Health.CheckDefaultIdentity = checkDefaultIdentity
+			case "readiness-issuer-window":
+				c.Health.IssuerReadinessWindow = &metav1.Duration{Duration: issuerReadinessWindow}
+			case "watch-namespaces":
+				c.Watch.Namespaces = nil
+				for _, ns := range strings.Split(watchNamespaces, ",") {
+					if ns = strings.TrimSpace(ns); ns != "" {
+						c.Watch.Namespaces = append(c.Watch.Namespaces, ns)
+					}
+				}
+			case "issuer-label-selector":
+				c.Watch.IssuerLabelSelector = issuerLabelSelector
+			case "instance-name":
+				c.Watch.InstanceName = instanceName
+			case "enable-sharding":
+				c.Sharding.Enabled = enableSharding
+			case "shards":
+				c.Sharding.Shards = shards
+			case "shard-lease-duration":
+				c.Sharding.LeaseDuration = &metav1.Duration{Duration: shardLeaseDuration}
+			case "shard-renew-period":
+				c.Sharding.RenewPeriod = &metav1.Duration{Duration: shardRenewPeriod}
+			case "shard-lease-namespace":
+				c.Sharding.Namespace = shardLeaseNamespace
+			}
+		})
+	}
+	overrideFromFlags(cfg)
+	if err := cfg.Validate(); err != nil {
+		setupLog.Error(err, "invalid configuration")
+		os.Exit(1)
+	}
+	awspca.SetDefaults(cfg.AWSDefaults())
+	awspca.SetIdempotencyTokenStrategy(awspca.IdempotencyTokenStrategy(cfg.Controller.IdempotencyTokenStrategy))
+
+	selector, err := labels.Parse(cfg.Watch.IssuerLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid issuer label selector")
+		os.Exit(1)
+	}
+	issuerFilter := controllers.IssuerFilter{
+		Selector:     selector,
+		InstanceName: cfg.Watch.InstanceName,
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: *cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	var auditLog *audit.Logger
+	if cfg.Audit.Path != "" {
+		auditLog, err = audit.Open(cfg.Audit.Path)
+		if err != nil {
+			setupLog.Error(err, "unable to open audit log")
+			os.Exit(1)
+		}
+	}
+
 	config := ctrl.GetConfigOrDie()
-	if disableClientSideRateLimiting {
+	if cfg.KubernetesClient.QPS != 0 {
+		config.QPS = cfg.KubernetesClient.QPS
+	}
+	if cfg.KubernetesClient.Burst != 0 {
+		config.Burst = cfg.KubernetesClient.Burst
+	}
+	if cfg.KubernetesClient.DisableRateLimiting {
 		// A negative QPS and Burst indicates that the client should not have a rate limiter.
 		// Ref: https://github.com/kubernetes/kubernetes/blob/v1.24.0/staging/src/k8s.io/client-go/rest/config.go#L354-L364
 		setupLog.Info("Disabling Kubernetes client rate limiter.")
 		config.QPS = -1
 		config.Burst = -1
 	}
+	healthChecker := &health.Checker{
+		IssuerWindow: cfg.Health.IssuerReadinessWindow.Duration,
+	}
+	if cfg.Health.CheckDefaultIdentity {
+		healthChecker.Probe = awspca.CheckDefaultIdentity
+	}
+
+	cacheOpts := cache.Options{
+		SyncPeriod: &cfg.Controller.SyncPeriod.Duration,
+	}
+	if len(cfg.Watch.Namespaces) > 0 {
+		cacheOpts.DefaultNamespaces = make(map[string]cache.Config, len(cfg.Watch.Namespaces))
+		for _, ns := range cfg.Watch.Namespaces {
+			cacheOpts.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	// Instances handling different issuers must not compete for one lease
+	leaderElectionID := "b858308c.awspca.cert-manager.io"
+	if cfg.Watch.InstanceName != "" {
+		leaderElectionID = cfg.Watch.InstanceName + "." + leaderElectionID
+	}
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
-			BindAddress: metricsAddr,
+			BindAddress: cfg.Metrics.BindAddress,
+			ExtraHandlers: map[string]http.Handler{
+				"/debug/issuers": healthChecker,
+			},
 		},
 		WebhookServer: webhook.NewServer(webhook.Options{
 			Port: 9443,
 		}),
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "b858308c.awspca.cert-manager.io",
+		HealthProbeBindAddress:     cfg.Health.BindAddress,
+		LeaderElection:             cfg.LeaderElection.Enabled,
+		LeaderElectionID:           leaderElectionID,
+		LeaderElectionNamespace:    cfg.LeaderElection.Namespace,
+		LeaderElectionResourceLock: cfg.LeaderElection.ResourceLock,
+		LeaseDuration:              &cfg.LeaderElection.LeaseDuration.Duration,
+		RenewDeadline:              &cfg.LeaderElection.RenewDeadline.Duration,
+		RetryPeriod:                &cfg.LeaderElection.RetryPeriod.Duration,
+		Cache:                      cacheOpts,
+		Controller: ctrlconfig.Controller{
+			MaxConcurrentReconciles: cfg.Controller.MaxConcurrentReconciles,
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if err := mgr.Add(metrics.LeaderRunnable{}); err != nil {
+		setupLog.Error(err, "unable to set up leader metric")
+		os.Exit(1)
+	}
+
+	if configFile != "" {
+		if err := mgr.Add(&issuerconfig.Watcher{
+			Path:     configFile,
+			Log:      ctrl.Log.WithName("config"),
+			Current:  cfg,
+			Override: overrideFromFlags,
+			Apply: func(c *issuerconfig.ControllerConfiguration) {
+				awspca.SetDefaults(c.AWSDefaults())
+			},
+		}); err != nil {
+			setupLog.Error(err, "unable to watch config file")
+			os.Exit(1)
+		}
+	}
+
+	var sharder *sharding.Sharder
+	if cfg.Sharding.Enabled {
+		sharder, err = newSharder(config, cfg)
+		if err != nil {
+			setupLog.Error(err, "unable to set up sharding")
+			os.Exit(1)
+		}
+		if err := mgr.Add(sharder); err != nil {
+			setupLog.Error(err, "unable to set up sharding")
+			os.Exit(1)
+		}
+	}
+
+	pollers := awspca.NewPollers()
+	if sharder != nil {
+		pollers.Owns = sharder.Owns
+	}
+	if err := mgr.Add(pollers); err != nil {
+		setupLog.Error(err, "unable to set up certificate polling")
+		os.Exit(1)
+	}
+	awspca.SetPollers(pollers)
+
 	genericIssuerController := &controllers.GenericIssuerReconciler{
-		Client:            mgr.GetClient(),
-		Log:               ctrl.Log.WithName("controllers").WithName("GenericIssuer"),
-		Scheme:            mgr.GetScheme(),
-		Recorder:          mgr.GetEventRecorderFor("awspcaissuer-controller"),
-		GetCallerIdentity: true,
+		Client:             mgr.GetClient(),
+		Log:                ctrl.Log.WithName("controllers").WithName("GenericIssuer"),
+		Scheme:             mgr.GetScheme(),
+		Recorder:           mgr.GetEventRecorderFor("awspcaissuer-controller"),
+		GetCallerIdentity:  true,
+		TrackCACertificate: true,
+		RequireRevocation:  cfg.Controller.RequireCARevocation,
+		IssuerFilter:       issuerFilter,
+		Health:             healthChecker,
 	}
 	if err = (&controllers.AWSPCAIssuerReconciler{
 		Client:            mgr.GetClient(),
@@ -135,18 +419,34 @@ func main() {
 		Recorder: mgr.GetEventRecorderFor("awspcaissuer-controller"),
 
 		Clock:                  clock.RealClock{},
-		CheckApprovedCondition: !disableApprovedCheck,
+		CheckApprovedCondition: !cfg.Controller.DisableApprovedCheck,
+		IssuerFilter:           issuerFilter,
+		Audit:                  auditLog,
+		Sharder:                sharder,
+		Pollers:                pollers,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CertificateRequest")
 		os.Exit(1)
 	}
+	if cfg.Controller.EnableApprover {
+		if err = (&controllers.CertificateRequestApprover{
+			Client:       mgr.GetClient(),
+			Log:          ctrl.Log.WithName("controllers").WithName("CertificateRequestApprover"),
+			Scheme:       mgr.GetScheme(),
+			Recorder:     mgr.GetEventRecorderFor("awspcaissuer-approver"),
+			IssuerFilter: issuerFilter,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "CertificateRequestApprover")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("check", healthz.Ping); err != nil {
+	if err := mgr.AddReadyzCheck("aws", healthChecker.Check); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
@@ -156,4 +456,49 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "problem flushing traces")
+	}
+	if err := auditLog.Close(); err != nil {
+		setupLog.Error(err, "problem closing audit log")
+	}
+}
+
+// newSharder returns the Sharder of this replica. The leases are read and
+// written directly, as the namespace they are in may not be cached.
+func newSharder(config *rest.Config, cfg *issuerconfig.ControllerConfiguration) (*sharding.Sharder, error) {
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := cfg.Sharding.Namespace
+	if namespace == "" {
+		namespace = cfg.LeaderElection.Namespace
+	}
+	if namespace == "" {
+		if namespace, err = sharding.InClusterNamespace(); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	// Instances handling different issuers shard their CertificateRequests
+	// separately
+	name := "awspca-issuer"
+	if cfg.Watch.InstanceName != "" {
+		name = cfg.Watch.InstanceName + "-" + name
+	}
+
+	return sharding.New(c, ctrl.Log.WithName("sharding"), sharding.Options{
+		Name:          name,
+		Namespace:     namespace,
+		Identity:      strings.ToLower(hostname) + "-" + string(uuid.NewUUID())[:8],
+		Shards:        cfg.Sharding.Shards,
+		LeaseDuration: cfg.Sharding.LeaseDuration.Duration,
+		RenewPeriod:   cfg.Sharding.RenewPeriod.Duration,
+	}), nil
 }
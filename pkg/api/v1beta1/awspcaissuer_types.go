@@ -40,11 +40,164 @@ type AWSPCAIssuerSpec struct {
 	// Specifies the ARN of role to assume when issuing certificates.
 	// +optional
 	Role string `json:"role,omitempty"`
+	// Specifies tags attributing the AWS calls of this issuer, for example to a
+	// tenant. They are attached as session tags when assuming the role, which
+	// requires its trust policy to allow sts:TagSession, and are added to the
+	// user agent of every call to PCA, which CloudTrail records.
+	// +kubebuilder:validation:MaxProperties=50
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// Specifies the scheme of the client request identifier added to the user
+	// agent of the calls to PCA made for a CertificateRequest, as
+	// client-request-id, which CloudTrail records. It is a Go template over
+	// .Namespace, .CertificateRequest and .Certificate, the name of the
+	// cert-manager Certificate of the request, if any. For example
+	// "{{ .Namespace }}.{{ .Certificate }}".
+	// +kubebuilder:validation:MaxLength=256
+	// +optional
+	ClientRequestID string `json:"clientRequestID,omitempty"`
 	// Specifies PCA template configuration for this issuer.
 	// +optional
 	PCATemplate *PCATemplate `json:"pcaTemplate,omitempty"`
+	// Specifies how the certificate chain returned by PCA is written to CertificateRequests.
+	// +optional
+	CertificateChain *CertificateChain `json:"certificateChain,omitempty"`
+	// Specifies a ConfigMap or Secret the CA certificate and chain are published to.
+	// +optional
+	TrustBundle *TrustBundle `json:"trustBundle,omitempty"`
+	// Specifies whether Certificates issued under a previous CA certificate are
+	// annotated and re-issued when the CA certificate changes.
+	// +optional
+	ReissueOnCARotation bool `json:"reissueOnCARotation,omitempty"`
+	// Specifies whether the revocation configuration of the CA is reported in
+	// the issuer status, and whether the issuer only becomes Ready when the CA
+	// has a CRL or OCSP enabled. Defaults to None.
+	// +kubebuilder:validation:Enum=None;Report;Require
+	// +optional
+	RevocationCheck RevocationCheck `json:"revocationCheck,omitempty"`
+	// Restricts which requesters may use this issuer, and optionally which
+	// templates. A CertificateRequest is denied unless a rule matches it.
+	// All requesters are allowed if empty.
+	// +optional
+	Authorization []AuthorizationRule `json:"authorization,omitempty"`
+	// Specifies the CertificateRequests the built-in approver approves for
+	// this issuer. Others are denied. The built-in approver ignores issuers
+	// without a policy.
+	// +optional
+	ApprovalPolicy *ApprovalPolicy `json:"approvalPolicy,omitempty"`
+	// Specifies that this issuer issues SPIFFE X.509 SVIDs, whose only SAN is
+	// the SPIFFE ID of the service account that requested them.
+	// +optional
+	SPIFFE *SPIFFE `json:"spiffe,omitempty"`
+	// Specifies that CertificateRequests are evaluated but not submitted to
+	// PCA. The IssueCertificate input is recorded on each request, which is
+	// then marked as failed.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+	// Specifies what happens when the issuer is deleted while some of its
+	// CertificateRequests are not yet Ready, Failed or Denied. Warn records
+	// a warning event and deletes the issuer, Block keeps the issuer until
+	// they are. Defaults to Warn.
+	// +kubebuilder:validation:Enum=Warn;Block
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// SPIFFE configures the issuance of SPIFFE X.509 SVIDs
+type SPIFFE struct {
+	// Specifies the trust domain of the SPIFFE IDs, for example cluster.local.
+	// +kubebuilder:validation:MinLength=1
+	TrustDomain string `json:"trustDomain"`
+}
+
+// ApprovalPolicy defines the CertificateRequests the built-in approver
+// approves. In patterns, * matches any characters, except dots in DNS names.
+type ApprovalPolicy struct {
+	// Specifies patterns the common name must match, for example
+	// *.example.com. A common name matching allowedDNSNames is also allowed.
+	// +optional
+	AllowedCommonNames []string `json:"allowedCommonNames,omitempty"`
+	// Specifies patterns the DNS names must match, for example *.example.com.
+	// No DNS names are allowed if empty.
+	// +optional
+	AllowedDNSNames []string `json:"allowedDNSNames,omitempty"`
+	// Specifies CIDR ranges the IP addresses must be in, for example
+	// 10.0.0.0/8. No IP addresses are allowed if empty.
+	// +optional
+	AllowedIPRanges []string `json:"allowedIPRanges,omitempty"`
+	// Specifies patterns the URIs must match, for example
+	// spiffe://cluster.local/ns/*. No URIs are allowed if empty.
+	// +optional
+	AllowedURIs []string `json:"allowedURIs,omitempty"`
+	// Specifies patterns the email addresses must match, for example
+	// *@example.com. No email addresses are allowed if empty.
+	// +optional
+	AllowedEmailAddresses []string `json:"allowedEmailAddresses,omitempty"`
+	// Specifies the key usages that may be requested, for example
+	// server auth. All usages are allowed if empty.
+	// +optional
+	AllowedUsages []string `json:"allowedUsages,omitempty"`
+	// Specifies the maximum duration that may be requested. Requests without
+	// a duration are checked with the default duration. Any duration is
+	// allowed if unset.
+	// +optional
+	MaxDuration *metav1.Duration `json:"maxDuration,omitempty"`
+	// Specifies whether CA certificates may be requested.
+	// +optional
+	AllowCA bool `json:"allowCA,omitempty"`
+}
+
+// AuthorizationRule allows the requesters it matches to use an issuer. A
+// rule without users, groups or service accounts matches every requester.
+type AuthorizationRule struct {
+	// Specifies the usernames allowed, as recorded in spec.username of the CertificateRequest.
+	// +optional
+	Users []string `json:"users,omitempty"`
+	// Specifies the groups whose members are allowed.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// Specifies the service accounts allowed.
+	// +optional
+	ServiceAccounts []ServiceAccountReference `json:"serviceAccounts,omitempty"`
+	// Specifies the PCA templates the matched requesters may use, for example
+	// EndEntityCertificate/V1. All templates are allowed if empty.
+	// +optional
+	Templates []string `json:"templates,omitempty"`
+}
+
+// ServiceAccountReference refers to a service account
+type ServiceAccountReference struct {
+	// Specifies the namespace of the service account.
+	Namespace string `json:"namespace"`
+	// Specifies the name of the service account.
+	Name string `json:"name"`
 }
 
+// RevocationCheck selects how the revocation configuration of the CA is checked
+type RevocationCheck string
+
+const (
+	// RevocationCheckNone does not check the revocation configuration of the CA
+	RevocationCheckNone RevocationCheck = "None"
+	// RevocationCheckReport reports the revocation configuration of the CA in the issuer status
+	RevocationCheckReport RevocationCheck = "Report"
+	// RevocationCheckRequire reports the revocation configuration of the CA
+	// and refuses to become Ready when neither a CRL nor OCSP is enabled
+	RevocationCheckRequire RevocationCheck = "Require"
+)
+
+// DeletionPolicy selects how the deletion of an issuer with CertificateRequests
+// in flight is handled
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyWarn records a warning event and deletes the issuer
+	DeletionPolicyWarn DeletionPolicy = "Warn"
+	// DeletionPolicyBlock keeps the issuer until its CertificateRequests are
+	// Ready, Failed or Denied
+	DeletionPolicyBlock DeletionPolicy = "Block"
+)
+
 // PCATemplate defines PCA template configuration
 type PCATemplate struct {
 	// Specifies the default template name for all certificate requests made to this issuer.
@@ -53,6 +206,80 @@ type PCATemplate struct {
 	DefaultTemplateName string `json:"defaultTemplateName,omitempty"`
 }
 
+// CertificateChainLayout selects the certificates written to status.certificate
+type CertificateChainLayout string
+
+const (
+	// CertificateChainLayoutLeaf writes only the issued certificate
+	CertificateChainLayoutLeaf CertificateChainLayout = "Leaf"
+	// CertificateChainLayoutLeafAndIntermediates writes the issued certificate followed by the intermediate CAs
+	CertificateChainLayoutLeafAndIntermediates CertificateChainLayout = "LeafAndIntermediates"
+	// CertificateChainLayoutFullChain writes the issued certificate followed by the intermediate CAs and the root CA
+	CertificateChainLayoutFullChain CertificateChainLayout = "FullChain"
+)
+
+// CALayout selects the certificates written to status.ca
+type CALayout string
+
+const (
+	// CALayoutRoot writes only the root CA
+	CALayoutRoot CALayout = "Root"
+	// CALayoutChain writes the intermediate CAs followed by the root CA
+	CALayoutChain CALayout = "Chain"
+)
+
+// CertificateChain defines how the certificate chain returned by PCA is laid out
+type CertificateChain struct {
+	// Specifies the certificates written to status.certificate.
+	// Defaults to LeafAndIntermediates.
+	// +kubebuilder:validation:Enum=Leaf;LeafAndIntermediates;FullChain
+	// +optional
+	Certificate CertificateChainLayout `json:"certificate,omitempty"`
+	// Specifies the certificates written to status.ca.
+	// Defaults to Root.
+	// +kubebuilder:validation:Enum=Root;Chain
+	// +optional
+	CA CALayout `json:"ca,omitempty"`
+}
+
+// TrustBundleKind selects the kind of object a trust bundle is written to
+type TrustBundleKind string
+
+const (
+	// TrustBundleKindConfigMap writes the trust bundle to a ConfigMap
+	TrustBundleKindConfigMap TrustBundleKind = "ConfigMap"
+	// TrustBundleKindSecret writes the trust bundle to a Secret
+	TrustBundleKindSecret TrustBundleKind = "Secret"
+)
+
+// TrustBundle defines where the CA certificate and chain of the issuer are published
+type TrustBundle struct {
+	// Specifies the kind of object the trust bundle is written to.
+	// Defaults to ConfigMap.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +optional
+	Kind TrustBundleKind `json:"kind,omitempty"`
+	// Specifies the name of the ConfigMap or Secret.
+	Name string `json:"name"`
+	// Specifies the key the PEM encoded trust bundle is written to.
+	// Defaults to ca.crt.
+	// +optional
+	Key string `json:"key,omitempty"`
+	// Selects the namespaces the trust bundle is published to. Required for
+	// AWSPCAClusterIssuers and not supported for AWSPCAIssuers, which publish
+	// to their own namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Specifies how often the CA certificate is fetched from PCA.
+	// Defaults to 1h.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+	// Specifies that the published trust bundles are kept when the issuer
+	// is deleted. By default they are deleted along with the issuer.
+	// +optional
+	Retain bool `json:"retain,omitempty"`
+}
+
 // AWSCredentialsSecretReference defines the secret used by the issuer
 type AWSCredentialsSecretReference struct {
 	v1.SecretReference `json:""`
@@ -70,10 +297,39 @@ type AWSPCAIssuerStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// SHA-256 fingerprint of the CA certificate last observed in PCA.
+	// +optional
+	CACertificateFingerprint string `json:"caCertificateFingerprint,omitempty"`
+	// Revocation configuration of the CA last observed in PCA, reported when
+	// revocationCheck is Report or Require.
+	// +optional
+	Revocation *RevocationStatus `json:"revocation,omitempty"`
+}
+
+// RevocationStatus describes the revocation configuration of the CA
+type RevocationStatus struct {
+	// Whether the CA publishes a CRL.
+	CRLEnabled bool `json:"crlEnabled"`
+	// URL of the CRL, included in the certificates issued by the CA unless
+	// the CRL distribution point extension is omitted.
+	// +optional
+	CRLDistributionPoint string `json:"crlDistributionPoint,omitempty"`
+	// Whether the CA has an OCSP responder.
+	OCSPEnabled bool `json:"ocspEnabled"`
+	// URL of the OCSP responder included in the certificates issued by the CA.
+	// +optional
+	OCSPURL string `json:"ocspURL,omitempty"`
 }
 
-// ConditionTypeReady is the default condition type for the CRs
-const ConditionTypeReady = "Ready"
+const (
+	// ConditionTypeReady is the default condition type for the CRs
+	ConditionTypeReady = "Ready"
+	// ConditionTypeCACertificateRotated is set when the CA certificate of the issuer changes
+	ConditionTypeCACertificateRotated = "CACertificateRotated"
+	// ConditionTypeTrustBundlePublished reports whether the trust bundle of the issuer was published to
+	// every selected namespace
+	ConditionTypeTrustBundlePublished = "TrustBundlePublished"
+)
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
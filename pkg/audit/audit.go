@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"k8s.io/utils/clock"
+)
+
+// Decision is the outcome of a CertificateRequest recorded in the audit log
+type Decision string
+
+const (
+	// DecisionApproved is recorded when an approved CertificateRequest is
+	// submitted to PCA
+	DecisionApproved Decision = "approved"
+	// DecisionDenied is recorded when a CertificateRequest was denied by an
+	// approval controller
+	DecisionDenied Decision = "denied"
+	// DecisionFailed is recorded when a CertificateRequest is marked failed
+	DecisionFailed Decision = "failed"
+	// DecisionIssued is recorded when the certificate of a
+	// CertificateRequest is issued
+	DecisionIssued Decision = "issued"
+	// DecisionDryRun is recorded when a CertificateRequest was evaluated in
+	// dry-run mode and not submitted to PCA
+	DecisionDryRun Decision = "dry-run"
+)
+
+// Record is one line of the audit log
+type Record struct {
+	Time     time.Time `json:"time"`
+	Decision Decision  `json:"decision"`
+	Reason   string    `json:"reason,omitempty"`
+
+	Namespace          string `json:"namespace"`
+	CertificateRequest string `json:"certificateRequest"`
+	UID                string `json:"uid,omitempty"`
+
+	Username string   `json:"username,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+
+	IssuerKind string `json:"issuerKind,omitempty"`
+	IssuerName string `json:"issuerName,omitempty"`
+	CAArn      string `json:"caArn,omitempty"`
+	Template   string `json:"template,omitempty"`
+
+	CommonName     string   `json:"commonName,omitempty"`
+	DNSNames       []string `json:"dnsNames,omitempty"`
+	IPAddresses    []string `json:"ipAddresses,omitempty"`
+	URIs           []string `json:"uris,omitempty"`
+	EmailAddresses []string `json:"emailAddresses,omitempty"`
+
+	SerialNumber   string `json:"serialNumber,omitempty"`
+	CertificateArn string `json:"certificateArn,omitempty"`
+}
+
+// NewRecord returns a record describing the requester and the requested
+// names of a CertificateRequest
+func NewRecord(cr *cmapi.CertificateRequest, decision Decision, reason string) Record {
+	record := Record{
+		Decision:           decision,
+		Reason:             reason,
+		Namespace:          cr.Namespace,
+		CertificateRequest: cr.Name,
+		UID:                string(cr.UID),
+		Username:           cr.Spec.Username,
+		Groups:             cr.Spec.Groups,
+		IssuerKind:         cr.Spec.IssuerRef.Kind,
+		IssuerName:         cr.Spec.IssuerRef.Name,
+	}
+
+	// The CSR is only decoded for the audit log, an invalid CSR is reported
+	// by the failure of the request itself
+	block, _ := pem.Decode(cr.Spec.Request)
+	if block == nil {
+		return record
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return record
+	}
+	record.CommonName = csr.Subject.CommonName
+	record.DNSNames = csr.DNSNames
+	record.EmailAddresses = csr.EmailAddresses
+	for _, ip := range csr.IPAddresses {
+		record.IPAddresses = append(record.IPAddresses, ip.String())
+	}
+	for _, uri := range csr.URIs {
+		record.URIs = append(record.URIs, uri.String())
+	}
+	return record
+}
+
+// Logger writes records to an audit log as JSON lines. A nil Logger
+// discards all records.
+type Logger struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+	clock   clock.PassiveClock
+}
+
+// New returns a Logger writing to w
+func New(w io.Writer) *Logger {
+	return &Logger{encoder: json.NewEncoder(w), clock: clock.RealClock{}}
+}
+
+// Open returns a Logger appending to the file at path, or writing to stdout
+// if path is "-"
+func Open(path string) (*Logger, error) {
+	if path == "-" {
+		return New(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l := New(f)
+	l.closer = f
+	return l, nil
+}
+
+// Log writes a record to the audit log, setting its time if unset
+func (l *Logger) Log(record Record) error {
+	if l == nil {
+		return nil
+	}
+	if record.Time.IsZero() {
+		record.Time = l.clock.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.encoder.Encode(record)
+}
+
+// Close closes the file the audit log is written to
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
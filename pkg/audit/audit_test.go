@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestNewRecord(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	spiffe, err := url.Parse("spiffe://cluster.local/ns/ns1/sa/app")
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: "example.com"},
+		DNSNames:       []string{"example.com", "www.example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		URIs:           []*url.URL{spiffe},
+		EmailAddresses: []string{"admin@example.com"},
+	}, key)
+	require.NoError(t, err)
+
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cr1", UID: "uid1"},
+		Spec: cmapi.CertificateRequestSpec{
+			Request:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+			Username:  "system:serviceaccount:cert-manager:cert-manager",
+			Groups:    []string{"system:serviceaccounts"},
+			IssuerRef: cmmeta.ObjectReference{Kind: "AWSPCAClusterIssuer", Name: "issuer1"},
+		},
+	}
+
+	assert.Equal(t, Record{
+		Decision:           DecisionApproved,
+		Reason:             "reason",
+		Namespace:          "ns1",
+		CertificateRequest: "cr1",
+		UID:                "uid1",
+		Username:           "system:serviceaccount:cert-manager:cert-manager",
+		Groups:             []string{"system:serviceaccounts"},
+		IssuerKind:         "AWSPCAClusterIssuer",
+		IssuerName:         "issuer1",
+		CommonName:         "example.com",
+		DNSNames:           []string{"example.com", "www.example.com"},
+		IPAddresses:        []string{"10.0.0.1"},
+		URIs:               []string{"spiffe://cluster.local/ns/ns1/sa/app"},
+		EmailAddresses:     []string{"admin@example.com"},
+	}, NewRecord(cr, DecisionApproved, "reason"))
+
+	cr.Spec.Request = []byte("not a CSR")
+	record := NewRecord(cr, DecisionFailed, "invalid CSR")
+	assert.Equal(t, "cr1", record.CertificateRequest)
+	assert.Empty(t, record.DNSNames)
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf)
+	l.clock = clocktesting.NewFakePassiveClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	require.NoError(t, l.Log(Record{Decision: DecisionIssued, Namespace: "ns1", CertificateRequest: "cr1", SerialNumber: "01"}))
+	require.NoError(t, l.Log(Record{Decision: DecisionDenied, Namespace: "ns1", CertificateRequest: "cr2"}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"time":"2024-05-01T12:00:00Z","decision":"issued","namespace":"ns1","certificateRequest":"cr1","serialNumber":"01"}`, lines[0])
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, DecisionDenied, record.Decision)
+
+	var disabled *Logger
+	assert.NoError(t, disabled.Log(Record{Decision: DecisionIssued}))
+	assert.NoError(t, disabled.Close())
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for _, name := range []string{"cr1", "cr2"} {
+		l, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, l.Log(Record{Decision: DecisionIssued, CertificateRequest: name}))
+		require.NoError(t, l.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"), "records are appended")
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Error(t, err)
+}
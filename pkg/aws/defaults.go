@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults holds the values used when neither the issuer nor the
+// CertificateRequest specify one
+type Defaults struct {
+	// Region is used by issuers without a region
+	Region string
+	// Duration is used by CertificateRequests without a duration
+	Duration time.Duration
+	// TemplateName is used by issuers without a default template name
+	TemplateName string
+}
+
+var (
+	defaultsMu sync.RWMutex
+	defaults   = Defaults{Duration: DEFAULT_DURATION * time.Second}
+)
+
+// SetDefaults replaces the defaults. It is safe to call while certificates
+// are being issued; a zero duration restores the built-in default. A change of
+// the region evicts the provisioners, as those of issuers without a region
+// were created for the previous one.
+func SetDefaults(d Defaults) {
+	if d.Duration == 0 {
+		d.Duration = DEFAULT_DURATION * time.Second
+	}
+	defaultsMu.Lock()
+	regionChanged := d.Region != defaults.Region
+	defaults = d
+	defaultsMu.Unlock()
+
+	if regionChanged {
+		evictProvisioners()
+	}
+}
+
+// GetDefaults returns the current defaults
+func GetDefaults() Defaults {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return defaults
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DryRun builds the IssueCertificate input that Sign would send to PCA and
+// records it on the certificate request, without issuing a certificate. The
+// CSR is left out of the recorded input since it is already in the request.
+func (p *PCAProvisioner) DryRun(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string, log logr.Logger) error {
+	issueParams, err := p.issueCertificateInput(ctx, cr, pcaTemplateName)
+	if err != nil {
+		return err
+	}
+
+	recorded := *issueParams
+	recorded.Csr = nil
+	input, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, DryRunInputAnnotation, string(input))
+
+	log.Info("Dry run, not issuing certificate", "input", string(input))
+
+	return nil
+}
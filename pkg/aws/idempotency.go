@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+// IdempotencyTokenStrategy selects what the idempotency token passed to
+// IssueCertificate is derived from
+type IdempotencyTokenStrategy string
+
+const (
+	// IdempotencyTokenStrategyRequest derives the token from the namespace,
+	// name, UID and CSR of the CertificateRequest, so that a recreated
+	// CertificateRequest is never treated as a retry of an earlier one
+	IdempotencyTokenStrategyRequest IdempotencyTokenStrategy = "Request"
+	// IdempotencyTokenStrategyName derives the token from the namespace and
+	// name of the CertificateRequest only, as earlier releases did
+	IdempotencyTokenStrategyName IdempotencyTokenStrategy = "Name"
+)
+
+// IdempotencyTokenStrategies lists the supported strategies
+var IdempotencyTokenStrategies = []IdempotencyTokenStrategy{IdempotencyTokenStrategyRequest, IdempotencyTokenStrategyName}
+
+var idempotencyTokenStrategy atomic.Value
+
+// SetIdempotencyTokenStrategy selects the strategy used for all following
+// IssueCertificate calls
+func SetIdempotencyTokenStrategy(strategy IdempotencyTokenStrategy) {
+	idempotencyTokenStrategy.Store(strategy)
+}
+
+// GetIdempotencyTokenStrategy returns the selected strategy, which defaults
+// to IdempotencyTokenStrategyRequest
+func GetIdempotencyTokenStrategy() IdempotencyTokenStrategy {
+	if strategy, ok := idempotencyTokenStrategy.Load().(IdempotencyTokenStrategy); ok {
+		return strategy
+	}
+	return IdempotencyTokenStrategyRequest
+}
+
+// idempotencyToken is limited to 64 ASCII characters, so make a fixed length hash.
+// @see: https://docs.aws.amazon.com/AWSEC2/latest/APIReference/Run_Instance_Idempotency.html
+func idempotencyToken(cr *cmapi.CertificateRequest) string {
+	return idempotencyTokenFor(cr, GetIdempotencyTokenStrategy())
+}
+
+// idempotencyTokenFor returns the idempotency token of cr with strategy
+func idempotencyTokenFor(cr *cmapi.CertificateRequest, strategy IdempotencyTokenStrategy) string {
+	token := []byte(cr.ObjectMeta.Namespace + "/" + cr.ObjectMeta.Name)
+	if strategy == IdempotencyTokenStrategyRequest {
+		csrHash := sha256.Sum256(cr.Spec.Request)
+		token = fmt.Appendf(token, "/%s/%x", cr.ObjectMeta.UID, csrHash)
+	}
+	fullHash := fmt.Sprintf("%x", sha256.Sum256(token))
+	return fullHash[:36] // Truncate to 36 characters
+}
+
+// IdempotencyToken returns the idempotency token passed to IssueCertificate
+// for cr with the selected strategy
+func IdempotencyToken(cr *cmapi.CertificateRequest) string {
+	return idempotencyToken(cr)
+}
+
+// tokenMayBeReused reports whether PCA may have answered the IssueCertificate
+// call of cr with the certificate issued for an earlier CertificateRequest.
+// That requires a token derived from the namespace and name only, which a
+// CertificateRequest recreated with the same name shares, and an issuance
+// started within IdempotencyWindow of the creation of cr, as the earlier
+// request was submitted before cr was created.
+func tokenMayBeReused(cr *cmapi.CertificateRequest) bool {
+	annotations := cr.GetAnnotations()
+	if annotations[IdempotencyTokenAnnotation] != idempotencyTokenFor(cr, IdempotencyTokenStrategyName) {
+		return false
+	}
+	started, err := time.Parse(time.RFC3339, annotations[IssuanceStartedAnnotation])
+	if err != nil {
+		return false
+	}
+	return started.Sub(cr.CreationTimestamp.Time) <= IdempotencyWindow
+}
+
+// IdempotencyWindow is how long PCA returns the certificate already issued for
+// an idempotency token, rather than issuing another one
+const IdempotencyWindow = 5 * time.Minute
+
+// issuanceTTL is how long the certificate ARN returned for an idempotency
+// token is remembered
+const issuanceTTL = time.Hour
+
+// issuances maps idempotency tokens to the certificate ARN returned by
+// IssueCertificate, so that signing a CertificateRequest again, because its
+// ARN annotation was not persisted or is not yet visible in the cache, does
+// not issue another certificate once PCA has forgotten the token. It only
+// lives as long as the process: after a restart, a leader failover or a shard
+// handover, recovery depends on PCA's IdempotencyWindow alone.
+var issuances sync.Map
+
+type issuance struct {
+	certArn  string
+	issuedAt time.Time
+}
+
+// recordIssuance remembers the certificate ARN issued for token and forgets
+// expired issuances
+func recordIssuance(token, certArn string, now time.Time) {
+	issuances.Range(func(key, value any) bool {
+		if now.Sub(value.(issuance).issuedAt) > issuanceTTL {
+			issuances.Delete(key)
+		}
+		return true
+	})
+	issuances.Store(token, issuance{certArn: certArn, issuedAt: now})
+}
+
+// lookupIssuance returns the certificate ARN issued for token, if any
+func lookupIssuance(token string, now time.Time) (string, bool) {
+	value, ok := issuances.Load(token)
+	if !ok || now.Sub(value.(issuance).issuedAt) > issuanceTTL {
+		return "", false
+	}
+	return value.(issuance).certArn, true
+}
+
+// IssuanceRecorded reports whether this process remembers, at now, the
+// certificate ARN issued for token. The issuances are lost on a restart, a
+// leader failover or a shard handover, after which only the idempotency token
+// and issuance started annotations of the CertificateRequest tell that it was
+// submitted.
+func IssuanceRecorded(token string, now time.Time) bool {
+	_, ok := lookupIssuance(token, now)
+	return ok
+}
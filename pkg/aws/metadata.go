@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go/middleware"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+// Keys of the user agent metadata identifying the CertificateRequest a call
+// to PCA is made for
+const (
+	namespaceUserAgentKey          = "k8s-namespace"
+	certificateRequestUserAgentKey = "k8s-certificaterequest"
+	certificateUserAgentKey        = "k8s-certificate"
+	clientRequestIDUserAgentKey    = "client-request-id"
+	tagUserAgentKeyPrefix          = "tag-"
+)
+
+// sortedKeys returns the keys of tags in a stable order
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sessionTags converts the tags of an issuer to STS session tags
+func sessionTags(tags map[string]string) []ststypes.Tag {
+	var sessionTags []ststypes.Tag
+	for _, k := range sortedKeys(tags) {
+		sessionTags = append(sessionTags, ststypes.Tag{Key: &k, Value: aws.String(tags[k])})
+	}
+	return sessionTags
+}
+
+// tagsUserAgent adds the tags of an issuer to the user agent of every call
+func tagsUserAgent(tags map[string]string) []func(*middleware.Stack) error {
+	var options []func(*middleware.Stack) error
+	for _, k := range sortedKeys(tags) {
+		options = append(options, awsmiddleware.AddUserAgentKeyValue(
+			userAgentToken(tagUserAgentKeyPrefix+k), userAgentToken(tags[k])))
+	}
+	return options
+}
+
+// clientRequestIDData is what the client request identifier template of an
+// issuer is executed with
+type clientRequestIDData struct {
+	Namespace          string
+	CertificateRequest string
+	Certificate        string
+}
+
+// ErrInvalidClientRequestID is returned for a client request identifier
+// template that cannot be parsed or refers to unknown fields
+var ErrInvalidClientRequestID = errors.New("invalid clientRequestID")
+
+// ParseClientRequestID parses the client request identifier template of an
+// issuer. An empty template yields none.
+func ParseClientRequestID(scheme string) (*texttemplate.Template, error) {
+	if scheme == "" {
+		return nil, nil
+	}
+	tmpl, err := texttemplate.New("clientRequestID").Parse(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientRequestID, err)
+	}
+	// Catch references to unknown fields before any CertificateRequest
+	if err := tmpl.Execute(io.Discard, clientRequestIDData{}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientRequestID, err)
+	}
+	return tmpl, nil
+}
+
+// requestMetadata adds the namespace and name of the CertificateRequest, and
+// of the Certificate it belongs to, to the user agent of a call to PCA, so
+// that CloudTrail attributes the call to them. With a client request
+// identifier template, the identifier it yields is added as well.
+func requestMetadata(cr *cmapi.CertificateRequest, clientRequestID *texttemplate.Template) func(*acmpca.Options) {
+	certificate, hasCertificate := cr.GetAnnotations()[cmapi.CertificateNameKey]
+	var id strings.Builder
+	if clientRequestID != nil {
+		// The template was checked when it was parsed
+		_ = clientRequestID.Execute(&id, clientRequestIDData{
+			Namespace:          cr.Namespace,
+			CertificateRequest: cr.Name,
+			Certificate:        certificate,
+		})
+	}
+
+	return func(o *acmpca.Options) {
+		o.APIOptions = append(o.APIOptions,
+			awsmiddleware.AddUserAgentKeyValue(namespaceUserAgentKey, userAgentToken(cr.Namespace)),
+			awsmiddleware.AddUserAgentKeyValue(certificateRequestUserAgentKey, userAgentToken(cr.Name)),
+		)
+		if hasCertificate {
+			o.APIOptions = append(o.APIOptions,
+				awsmiddleware.AddUserAgentKeyValue(certificateUserAgentKey, userAgentToken(certificate)))
+		}
+		if id.Len() > 0 {
+			o.APIOptions = append(o.APIOptions,
+				awsmiddleware.AddUserAgentKeyValue(clientRequestIDUserAgentKey, userAgentToken(id.String())))
+		}
+	}
+}
+
+// userAgentToken replaces the characters that are not allowed in a user
+// agent token
+func userAgentToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			return r
+		}
+		return '-'
+	}, s)
+}
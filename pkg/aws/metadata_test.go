@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRequestMetadata(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Certificate":"cert","CertificateChain":"chain"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := acmpca.New(acmpca.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	}, acmpca.WithAPIOptions(tagsUserAgent(map[string]string{"tenant": "team a", "cost-center": "42"})...))
+
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns1",
+			Name:        "cr1",
+			Annotations: map[string]string{cmapi.CertificateNameKey: "cert1"},
+		},
+	}
+	_, err := client.GetCertificate(context.TODO(), &acmpca.GetCertificateInput{
+		CertificateArn:          aws.String(certArn),
+		CertificateAuthorityArn: aws.String(caArn),
+	}, requestMetadata(cr, nil))
+	require.NoError(t, err)
+
+	assert.Contains(t, userAgent, "k8s-namespace/ns1")
+	assert.Contains(t, userAgent, "k8s-certificaterequest/cr1")
+	assert.Contains(t, userAgent, "k8s-certificate/cert1")
+	assert.Contains(t, userAgent, "tag-tenant/team-a")
+	assert.Contains(t, userAgent, "tag-cost-center/42")
+	assert.NotContains(t, userAgent, clientRequestIDUserAgentKey)
+
+	type testCase struct {
+		scheme     string
+		cr         *cmapi.CertificateRequest
+		expectedID string
+	}
+
+	tests := map[string]testCase{
+		"namespace-and-certificate": {
+			scheme:     "{{ .Namespace }}.{{ .Certificate }}",
+			cr:         cr,
+			expectedID: "client-request-id/ns1.cert1",
+		},
+		"certificate-request": {
+			scheme:     "tenant-a/{{ .CertificateRequest }}",
+			cr:         cr,
+			expectedID: "client-request-id/tenant-a-cr1",
+		},
+		"without-certificate": {
+			scheme:     "{{ .Namespace }}.{{ .Certificate }}",
+			cr:         &cmapi.CertificateRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cr1"}},
+			expectedID: "client-request-id/ns1.",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := ParseClientRequestID(tc.scheme)
+			require.NoError(t, err)
+			_, err = client.GetCertificate(context.TODO(), &acmpca.GetCertificateInput{
+				CertificateArn:          aws.String(certArn),
+				CertificateAuthorityArn: aws.String(caArn),
+			}, requestMetadata(tc.cr, tmpl))
+			require.NoError(t, err)
+			assert.Contains(t, userAgent, tc.expectedID)
+		})
+	}
+}
+
+func TestParseClientRequestID(t *testing.T) {
+	tmpl, err := ParseClientRequestID("")
+	assert.NoError(t, err)
+	assert.Nil(t, tmpl)
+
+	_, err = ParseClientRequestID("{{ .Namespace }")
+	assert.ErrorIs(t, err, ErrInvalidClientRequestID)
+
+	_, err = ParseClientRequestID("{{ .Tenant }}")
+	assert.ErrorIs(t, err, ErrInvalidClientRequestID)
+}
+
+func TestSessionTags(t *testing.T) {
+	assert.Empty(t, sessionTags(nil))
+
+	tags := sessionTags(map[string]string{"tenant": "a", "cost-center": "42"})
+	require.Len(t, tags, 2)
+	assert.Equal(t, "cost-center", *tags[0].Key)
+	assert.Equal(t, "42", *tags[0].Value)
+	assert.Equal(t, "tenant", *tags[1].Key)
+	assert.Equal(t, "a", *tags[1].Value)
+}
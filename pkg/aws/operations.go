@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+// CertificateAuthority describes the CA of a provisioner
+type CertificateAuthority struct {
+	Arn              string
+	Type             string
+	Status           string
+	UsageMode        string
+	KeyAlgorithm     string
+	SigningAlgorithm string
+	NotBefore        *time.Time
+	NotAfter         *time.Time
+	Revocation       api.RevocationStatus
+}
+
+// DescribeCertificateAuthority returns the details of the CA
+func (p *PCAProvisioner) DescribeCertificateAuthority(ctx context.Context) (*CertificateAuthority, error) {
+	output, err := p.pcaClient.DescribeCertificateAuthority(ctx, &acmpca.DescribeCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pca := output.CertificateAuthority
+	ca := &CertificateAuthority{
+		Arn:        p.arn,
+		Type:       string(pca.Type),
+		Status:     string(pca.Status),
+		UsageMode:  string(pca.UsageMode),
+		NotBefore:  pca.NotBefore,
+		NotAfter:   pca.NotAfter,
+		Revocation: revocationStatus(p.arn, pca.RevocationConfiguration),
+	}
+	if config := pca.CertificateAuthorityConfiguration; config != nil {
+		ca.KeyAlgorithm = string(config.KeyAlgorithm)
+		ca.SigningAlgorithm = string(config.SigningAlgorithm)
+	}
+	return ca, nil
+}
+
+// GetCertificate returns the PEM encoded certificate and chain issued by the
+// CA with the given ARN, without verifying it against a CertificateRequest
+func (p *PCAProvisioner) GetCertificate(ctx context.Context, certArn string) ([]byte, []byte, error) {
+	output, err := p.pcaClient.GetCertificate(ctx, &acmpca.GetCertificateInput{
+		CertificateArn:          aws.String(certArn),
+		CertificateAuthorityArn: aws.String(p.arn),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(aws.ToString(output.Certificate) + "\n"), []byte(aws.ToString(output.CertificateChain) + "\n"), nil
+}
+
+// RevokeCertificate revokes the certificate with the given serial number,
+// formatted as colon separated hex bytes
+func (p *PCAProvisioner) RevokeCertificate(ctx context.Context, serial string, reason acmpcatypes.RevocationReason) error {
+	_, err := p.pcaClient.RevokeCertificate(ctx, &acmpca.RevokeCertificateInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+		CertificateSerial:       aws.String(serial),
+		RevocationReason:        reason,
+	})
+	return err
+}
+
+// CertificateSerialNumber returns the serial number of a PEM encoded
+// certificate in the format expected by RevokeCertificate
+func CertificateSerialNumber(certPem []byte) (string, error) {
+	cert, err := parseCertificate(certPem)
+	if err != nil {
+		return "", err
+	}
+	return formatSerialNumber(cert.SerialNumber), nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type operationsACMPCAClient struct {
+	workingACMPCAClient
+	revokeInput *acmpca.RevokeCertificateInput
+}
+
+func (m *operationsACMPCAClient) DescribeCertificateAuthority(_ context.Context, input *acmpca.DescribeCertificateAuthorityInput, _ ...func(*acmpca.Options)) (*acmpca.DescribeCertificateAuthorityOutput, error) {
+	return &acmpca.DescribeCertificateAuthorityOutput{
+		CertificateAuthority: &acmpcatypes.CertificateAuthority{
+			Arn:       input.CertificateAuthorityArn,
+			Type:      acmpcatypes.CertificateAuthorityTypeSubordinate,
+			Status:    acmpcatypes.CertificateAuthorityStatusActive,
+			UsageMode: acmpcatypes.CertificateAuthorityUsageModeGeneralPurpose,
+			NotAfter:  aws.Time(time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)),
+			CertificateAuthorityConfiguration: &acmpcatypes.CertificateAuthorityConfiguration{
+				KeyAlgorithm:     acmpcatypes.KeyAlgorithmEcPrime256v1,
+				SigningAlgorithm: acmpcatypes.SigningAlgorithmSha256withecdsa,
+			},
+		},
+	}, nil
+}
+
+func (m *operationsACMPCAClient) RevokeCertificate(_ context.Context, input *acmpca.RevokeCertificateInput, _ ...func(*acmpca.Options)) (*acmpca.RevokeCertificateOutput, error) {
+	m.revokeInput = input
+	return &acmpca.RevokeCertificateOutput{}, nil
+}
+
+func TestPCADescribeCertificateAuthority(t *testing.T) {
+	provisioner := PCAProvisioner{arn: caArn, pcaClient: &operationsACMPCAClient{}}
+
+	ca, err := provisioner.DescribeCertificateAuthority(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, &CertificateAuthority{
+		Arn:              caArn,
+		Type:             "SUBORDINATE",
+		Status:           "ACTIVE",
+		UsageMode:        "GENERAL_PURPOSE",
+		KeyAlgorithm:     "EC_prime256v1",
+		SigningAlgorithm: "SHA256WITHECDSA",
+		NotAfter:         aws.Time(time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}, ca)
+}
+
+func TestPCAGetCertificate(t *testing.T) {
+	provisioner := PCAProvisioner{arn: caArn, pcaClient: &workingACMPCAClient{}}
+
+	certPem, chainPem, err := provisioner.GetCertificate(context.TODO(), certArn)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(cert+"\n"), certPem)
+	assert.Equal(t, []byte(chain+"\n"), chainPem)
+
+	provisioner = PCAProvisioner{arn: caArn, pcaClient: &errorACMPCAClient{}}
+	_, _, err = provisioner.GetCertificate(context.TODO(), certArn)
+	assert.Error(t, err)
+}
+
+func TestPCARevokeCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, _, _ := generateTestChain(t, &x509.Certificate{
+		SerialNumber: big.NewInt(0x1234abcd),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, &key.PublicKey)
+
+	serial, err := CertificateSerialNumber([]byte(leaf))
+	require.NoError(t, err)
+	assert.Equal(t, "12:34:ab:cd", serial)
+
+	_, err = CertificateSerialNumber([]byte("not a certificate"))
+	assert.Error(t, err)
+
+	client := &operationsACMPCAClient{}
+	provisioner := PCAProvisioner{arn: caArn, pcaClient: client}
+	require.NoError(t, provisioner.RevokeCertificate(context.TODO(), serial, acmpcatypes.RevocationReasonKeyCompromise))
+	assert.Equal(t, &acmpca.RevokeCertificateInput{
+		CertificateAuthorityArn: aws.String(caArn),
+		CertificateSerial:       aws.String("12:34:ab:cd"),
+		RevocationReason:        acmpcatypes.RevocationReasonKeyCompromise,
+	}, client.revokeInput)
+}
+
+func TestRevocationStatus(t *testing.T) {
+	tests := map[string]struct {
+		arn      string
+		config   *acmpcatypes.RevocationConfiguration
+		expected api.RevocationStatus
+	}{
+		"none": {
+			arn: caArn,
+		},
+		"disabled": {
+			arn: caArn,
+			config: &acmpcatypes.RevocationConfiguration{
+				CrlConfiguration:  &acmpcatypes.CrlConfiguration{Enabled: aws.Bool(false)},
+				OcspConfiguration: &acmpcatypes.OcspConfiguration{Enabled: aws.Bool(false)},
+			},
+		},
+		"crl-and-ocsp": {
+			arn: caArn,
+			config: &acmpcatypes.RevocationConfiguration{
+				CrlConfiguration:  &acmpcatypes.CrlConfiguration{Enabled: aws.Bool(true), S3BucketName: aws.String("bucket")},
+				OcspConfiguration: &acmpcatypes.OcspConfiguration{Enabled: aws.Bool(true)},
+			},
+			expected: api.RevocationStatus{
+				CRLEnabled:           true,
+				CRLDistributionPoint: "http://bucket.s3.us-east-1.amazonaws.com/crl/12345678-1234-1234-1234-123456789012.crl",
+				OCSPEnabled:          true,
+				OCSPURL:              "http://ocsp.acm-pca.us-east-1.amazonaws.com",
+			},
+		},
+		"custom-cnames": {
+			arn: caArn,
+			config: &acmpcatypes.RevocationConfiguration{
+				CrlConfiguration: &acmpcatypes.CrlConfiguration{
+					Enabled:      aws.Bool(true),
+					S3BucketName: aws.String("bucket"),
+					CustomCname:  aws.String("crl.example.com"),
+					CustomPath:   aws.String("pki/"),
+				},
+				OcspConfiguration: &acmpcatypes.OcspConfiguration{Enabled: aws.Bool(true), OcspCustomCname: aws.String("ocsp.example.com")},
+			},
+			expected: api.RevocationStatus{
+				CRLEnabled:           true,
+				CRLDistributionPoint: "http://crl.example.com/pki/12345678-1234-1234-1234-123456789012.crl",
+				OCSPEnabled:          true,
+				OCSPURL:              "http://ocsp.example.com",
+			},
+		},
+		"crl-extension-omitted": {
+			arn: caArn,
+			config: &acmpcatypes.RevocationConfiguration{
+				CrlConfiguration: &acmpcatypes.CrlConfiguration{
+					Enabled:      aws.Bool(true),
+					S3BucketName: aws.String("bucket"),
+					CrlDistributionPointExtensionConfiguration: &acmpcatypes.CrlDistributionPointExtensionConfiguration{
+						OmitExtension: aws.Bool(true),
+					},
+				},
+			},
+			expected: api.RevocationStatus{CRLEnabled: true},
+		},
+		"china": {
+			arn: "arn:aws-cn:acm-pca:cn-north-1:account:certificate-authority/ca",
+			config: &acmpcatypes.RevocationConfiguration{
+				CrlConfiguration: &acmpcatypes.CrlConfiguration{Enabled: aws.Bool(true), S3BucketName: aws.String("bucket")},
+			},
+			expected: api.RevocationStatus{
+				CRLEnabled:           true,
+				CRLDistributionPoint: "http://bucket.s3.cn-north-1.amazonaws.com.cn/crl/ca.crl",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, revocationStatus(tc.arn, tc.config))
+		})
+	}
+}
@@ -19,11 +19,15 @@ package aws
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
+	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
 	"sync"
+	texttemplate "text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -48,6 +52,25 @@ import (
 
 const DEFAULT_DURATION = 30 * 24 * 3600
 
+// Annotations recorded on a CertificateRequest to link it back to the PCA
+// artefacts involved in its issuance.
+const (
+	CertificateArnAnnotation           = "aws-privateca-issuer/certificate-arn"
+	CertificateAuthorityArnAnnotation  = "aws-privateca-issuer/certificate-authority-arn"
+	TemplateArnAnnotation              = "aws-privateca-issuer/template-arn"
+	SigningAlgorithmAnnotation         = "aws-privateca-issuer/signing-algorithm"
+	IdempotencyTokenAnnotation         = "aws-privateca-issuer/idempotency-token"
+	SerialNumberAnnotation             = "aws-privateca-issuer/serial-number"
+	NotBeforeAnnotation                = "aws-privateca-issuer/not-before"
+	NotAfterAnnotation                 = "aws-privateca-issuer/not-after"
+	RequestedNotAfterAnnotation        = "aws-privateca-issuer/requested-not-after"
+	CACertificateFingerprintAnnotation = "aws-privateca-issuer/ca-certificate-fingerprint"
+	CARotatedAnnotation                = "aws-privateca-issuer/ca-rotated"
+	DryRunAnnotation                   = "aws-privateca-issuer/dry-run"
+	DryRunInputAnnotation              = "aws-privateca-issuer/dry-run-input"
+	IssuanceStartedAnnotation          = "aws-privateca-issuer/issuance-started"
+)
+
 var (
 	ErrNoSecretAccessKey = errors.New("no AWS Secret Access Key Found")
 	ErrNoAccessKeyID     = errors.New("no AWS Access Key ID Found")
@@ -55,10 +78,17 @@ var (
 
 var collection = new(sync.Map)
 
+// versions holds the configuration version the provisioners of the issuers
+// were last refreshed with
+var versions = new(sync.Map)
+
 // GenericProvisioner abstracts over the Provisioner type for mocking purposes
 type GenericProvisioner interface {
 	Get(ctx context.Context, cr *cmapi.CertificateRequest, certArn string, log logr.Logger) ([]byte, []byte, error)
 	Sign(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string, log logr.Logger) error
+	DryRun(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string, log logr.Logger) error
+	GetCACertificate(ctx context.Context) ([]byte, error)
+	DescribeCertificateAuthority(ctx context.Context) (*CertificateAuthority, error)
 }
 
 // acmPCAClient abstracts over the methods used from acmpca.Client
@@ -66,6 +96,8 @@ type acmPCAClient interface {
 	acmpca.GetCertificateAPIClient
 	DescribeCertificateAuthority(ctx context.Context, params *acmpca.DescribeCertificateAuthorityInput, optFns ...func(*acmpca.Options)) (*acmpca.DescribeCertificateAuthorityOutput, error)
 	IssueCertificate(ctx context.Context, params *acmpca.IssueCertificateInput, optFns ...func(*acmpca.Options)) (*acmpca.IssueCertificateOutput, error)
+	GetCertificateAuthorityCertificate(ctx context.Context, params *acmpca.GetCertificateAuthorityCertificateInput, optFns ...func(*acmpca.Options)) (*acmpca.GetCertificateAuthorityCertificateOutput, error)
+	RevokeCertificate(ctx context.Context, params *acmpca.RevokeCertificateInput, optFns ...func(*acmpca.Options)) (*acmpca.RevokeCertificateOutput, error)
 }
 
 // PCAProvisioner contains logic for issuing PCA certificates
@@ -73,7 +105,14 @@ type PCAProvisioner struct {
 	pcaClient        acmPCAClient
 	arn              string
 	signingAlgorithm *acmpcatypes.SigningAlgorithm
+	chain            api.CertificateChain
+	spiffe           *api.SPIFFE
 	clock            func() time.Time
+	// poller, if set, retrieves issued certificates in the background
+	poller *poller
+	// clientRequestID, if set, identifies the calls made for a
+	// CertificateRequest
+	clientRequestID *texttemplate.Template
 }
 
 func GetConfig(ctx context.Context, client client.Client, spec *api.AWSPCAIssuerSpec) (aws.Config, error) {
@@ -86,6 +125,7 @@ func GetConfig(ctx context.Context, client client.Client, spec *api.AWSPCAIssuer
 	cfg.Retryer = func() aws.Retryer {
 		return retry.AddWithErrorCodes(retry.NewStandard(), (*acmpcatypes.RequestInProgressException)(nil).ErrorCode())
 	}
+	cfg.APIOptions = append(cfg.APIOptions, addTracingMiddleware)
 
 	return cfg, nil
 }
@@ -94,6 +134,8 @@ func LoadConfig(ctx context.Context, client client.Client, spec *api.AWSPCAIssue
 	var configOptions []func(*config.LoadOptions) error
 	if spec.Region != "" {
 		configOptions = append(configOptions, config.WithRegion(spec.Region))
+	} else if region := GetDefaults().Region; region != "" {
+		configOptions = append(configOptions, config.WithRegion(region))
 	}
 
 	if spec.SecretRef.Name != "" {
@@ -137,7 +179,9 @@ func LoadConfig(ctx context.Context, client client.Client, spec *api.AWSPCAIssue
 
 	if spec.Role != "" {
 		stsService := sts.NewFromConfig(cfg)
-		creds := stscreds.NewAssumeRoleProvider(stsService, spec.Role)
+		creds := stscreds.NewAssumeRoleProvider(stsService, spec.Role, func(o *stscreds.AssumeRoleOptions) {
+			o.Tags = sessionTags(spec.Tags)
+		})
 		cfg.Credentials = aws.NewCredentialsCache(creds)
 	}
 
@@ -146,14 +190,60 @@ func LoadConfig(ctx context.Context, client client.Client, spec *api.AWSPCAIssue
 
 func ClearProvisioners() {
 	collection.Clear()
+	versions.Clear()
+	if s := activePollers.Load(); s != nil {
+		s.clear()
+	}
+}
+
+// evictProvisioners removes the provisioners of all issuers, so that they are
+// created again. The pollers are kept, and handed over to the next
+// provisioners.
+func evictProvisioners() {
+	collection.Clear()
+	versions.Clear()
 }
 
-// DeleteProvisioner will remove a provisioner if it already exists
+// DeleteProvisioner will remove a provisioner if it already exists, and stop
+// the poller of the issuer
 func DeleteProvisioner(ctx context.Context, client client.Client, name types.NamespacedName) {
 	_, exists := collection.Load(name)
 	if exists {
 		collection.Delete(name)
 	}
+	versions.Delete(name)
+	if s := activePollers.Load(); s != nil {
+		s.remove(name)
+	}
+}
+
+// ConfigVersion identifies the configuration the provisioner of an issuer is
+// created from: the generation of the issuer, the resourceVersion of the
+// Secret holding its credentials and the default region, if the issuer has
+// none.
+func ConfigVersion(ctx context.Context, client client.Client, generation int64, spec *api.AWSPCAIssuerSpec) (string, error) {
+	version := strconv.FormatInt(generation, 10)
+	if spec.Region == "" {
+		version += "/" + GetDefaults().Region
+	}
+	if spec.SecretRef.Name != "" {
+		secret := new(core.Secret)
+		if err := client.Get(ctx, types.NamespacedName{Namespace: spec.SecretRef.Namespace, Name: spec.SecretRef.Name}, secret); err != nil {
+			return "", fmt.Errorf("failed to retrieve secret: %v", err)
+		}
+		version += "/" + secret.ResourceVersion
+	}
+	return version, nil
+}
+
+// RefreshProvisioner removes the provisioner of an issuer if it was created
+// from another configuration version, as returned by ConfigVersion. The
+// poller of the issuer is kept, and handed over to the next provisioner.
+func RefreshProvisioner(name types.NamespacedName, version string) {
+	previous, loaded := versions.Swap(name, version)
+	if loaded && previous != version {
+		collection.Delete(name)
+	}
 }
 
 // GetProvisioner gets a provisioner that has previously been stored or creates a new one
@@ -164,53 +254,119 @@ func GetProvisioner(ctx context.Context, client client.Client, name types.Namesp
 		return p, nil
 	}
 
-	config, err := GetConfig(ctx, client, spec)
+	provisioner, err := NewProvisioner(ctx, client, spec)
 	if err != nil {
 		return nil, err
 	}
-
-	provisioner := &PCAProvisioner{
-		pcaClient: acmpca.NewFromConfig(config, acmpca.WithAPIOptions(
-			middleware.AddUserAgentKeyValue(injections.UserAgent, injections.PlugInVersion),
-		)),
-		arn: spec.Arn,
+	// The controllers wait for certificates through Pollers.Completed
+	if s := activePollers.Load(); s != nil {
+		provisioner.poller = s.pollerFor(name, provisioner)
 	}
 	collection.Store(name, provisioner)
 
 	return provisioner, nil
 }
 
-// idempotencyToken is limited to 64 ASCII characters, so make a fixed length hash.
-// @see: https://docs.aws.amazon.com/AWSEC2/latest/APIReference/Run_Instance_Idempotency.html
-func idempotencyToken(cr *cmapi.CertificateRequest) string {
-	token := []byte(cr.ObjectMeta.Namespace + "/" + cr.ObjectMeta.Name)
-	fullHash := fmt.Sprintf("%x", sha256.Sum256(token))
-	return fullHash[:36] // Truncate to 36 characters
+// NewProvisioner creates a provisioner for the CA of an issuer without
+// storing it
+func NewProvisioner(ctx context.Context, client client.Client, spec *api.AWSPCAIssuerSpec) (*PCAProvisioner, error) {
+	config, err := GetConfig(ctx, client, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	clientRequestID, err := ParseClientRequestID(spec.ClientRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	provisioner := &PCAProvisioner{
+		pcaClient: acmpca.NewFromConfig(config,
+			acmpca.WithAPIOptions(middleware.AddUserAgentKeyValue(injections.UserAgent, injections.PlugInVersion)),
+			acmpca.WithAPIOptions(tagsUserAgent(spec.Tags)...),
+		),
+		arn:             spec.Arn,
+		spiffe:          spec.SPIFFE,
+		clientRequestID: clientRequestID,
+	}
+	if spec.CertificateChain != nil {
+		provisioner.chain = *spec.CertificateChain
+	}
+	return provisioner, nil
 }
 
 // Sign takes a certificate request and signs it using PCA
 func (p *PCAProvisioner) Sign(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string, log logr.Logger) error {
+	issueParams, err := p.issueCertificateInput(ctx, cr, pcaTemplateName)
+	if err != nil {
+		return err
+	}
+	token := aws.ToString(issueParams.IdempotencyToken)
+
+	certArn, recovered := lookupIssuance(token, p.now())
+	if recovered {
+		log.Info("Recovered certificate arn of an earlier issuance: " + certArn)
+	} else {
+		issueOutput, err := p.pcaClient.IssueCertificate(ctx, issueParams, requestMetadata(cr, p.clientRequestID))
+		if err != nil {
+			return err
+		}
+		certArn = *issueOutput.CertificateArn
+		recordIssuance(token, certArn, p.now())
+	}
+
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, CertificateArnAnnotation, certArn)
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, CertificateAuthorityArnAnnotation, p.arn)
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, TemplateArnAnnotation, aws.ToString(issueParams.TemplateArn))
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, SigningAlgorithmAnnotation, string(*p.signingAlgorithm))
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, IdempotencyTokenAnnotation, token)
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, RequestedNotAfterAnnotation, time.Unix(*issueParams.Validity.Value, 0).UTC().Format(time.RFC3339))
+
+	log.Info("Issued certificate with arn: " + certArn)
+
+	return nil
+}
+
+// issueCertificateInput returns the input of the IssueCertificate call that
+// signs a certificate request
+func (p *PCAProvisioner) issueCertificateInput(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string) (*acmpca.IssueCertificateInput, error) {
 	block, _ := pem.Decode(cr.Spec.Request)
 	if block == nil {
-		return fmt.Errorf("failed to decode CSR")
+		return nil, fmt.Errorf("failed to decode CSR")
 	}
 
-	validityExpiration := int64(p.now().Unix()) + DEFAULT_DURATION
+	defaults := GetDefaults()
+	validityExpiration := int64(p.now().Unix()) + int64(defaults.Duration.Seconds())
 	if cr.Spec.Duration != nil {
 		validityExpiration = int64(p.now().Unix()) + int64(cr.Spec.Duration.Seconds())
 	}
 
-	// Consider it a "retry" if we try to re-create a cert with the same name in the same namespace
+	// Consider it a "retry" if the same request is signed again
 	token := idempotencyToken(cr)
 
 	err := getSigningAlgorithm(ctx, p)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var apiPassthrough *acmpcatypes.ApiPassthrough
+	if p.spiffe != nil {
+		id, err := SPIFFEID(p.spiffe.TrustDomain, cr)
+		if err != nil {
+			return nil, err
+		}
+		apiPassthrough = svidApiPassthrough(id)
+		if pcaTemplateName == "" {
+			pcaTemplateName = SPIFFETemplateName
+		}
 	}
 
+	if pcaTemplateName == "" {
+		pcaTemplateName = defaults.TemplateName
+	}
 	pcaTemplateArn := buildTemplateArn(p.arn, cr.Spec, pcaTemplateName)
 
-	issueParams := acmpca.IssueCertificateInput{
+	return &acmpca.IssueCertificateInput{
 		CertificateAuthorityArn: aws.String(p.arn),
 		SigningAlgorithm:        *p.signingAlgorithm,
 		TemplateArn:             aws.String(pcaTemplateArn),
@@ -220,28 +376,24 @@ func (p *PCAProvisioner) Sign(ctx context.Context, cr *cmapi.CertificateRequest,
 			Value: &validityExpiration,
 		},
 		IdempotencyToken: aws.String(token),
-	}
-
-	issueOutput, err := p.pcaClient.IssueCertificate(ctx, &issueParams)
-
-	if err != nil {
-		return err
-	}
-
-	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, "aws-privateca-issuer/certificate-arn", *issueOutput.CertificateArn)
-
-	log.Info("Issued certificate with arn: " + *issueOutput.CertificateArn)
-
-	return nil
+		ApiPassthrough:   apiPassthrough,
+	}, nil
 }
 
+// Get retrieves the certificate issued for a certificate request, along with
+// its CA. With a poller, Get returns ErrCertificatePending until the poller
+// has retrieved the certificate.
 func (p *PCAProvisioner) Get(ctx context.Context, cr *cmapi.CertificateRequest, certArn string, log logr.Logger) ([]byte, []byte, error) {
-	getParams := acmpca.GetCertificateInput{
-		CertificateArn:          aws.String(certArn),
-		CertificateAuthorityArn: aws.String(p.arn),
+	var getOutput *acmpca.GetCertificateOutput
+	var err error
+	if p.poller != nil {
+		getOutput, err = p.poller.get(cr, certArn, requestMetadata(cr, p.clientRequestID))
+	} else {
+		getOutput, err = p.pcaClient.GetCertificate(ctx, &acmpca.GetCertificateInput{
+			CertificateArn:          aws.String(certArn),
+			CertificateAuthorityArn: aws.String(p.arn),
+		}, requestMetadata(cr, p.clientRequestID))
 	}
-
-	getOutput, err := p.pcaClient.GetCertificate(ctx, &getParams)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -252,11 +404,56 @@ func (p *PCAProvisioner) Get(ctx context.Context, cr *cmapi.CertificateRequest,
 	if err != nil {
 		return nil, nil, err
 	}
-	certPem = append(certPem, chainIntCAs...)
+
+	switch p.chain.Certificate {
+	case api.CertificateChainLayoutLeaf:
+	case api.CertificateChainLayoutFullChain:
+		certPem = append(certPem, chainIntCAs...)
+		certPem = append(certPem, rootCA...)
+	default:
+		certPem = append(certPem, chainIntCAs...)
+	}
+
+	caPem := rootCA
+	if p.chain.CA == api.CALayoutChain {
+		caPem = append(append([]byte{}, chainIntCAs...), rootCA...)
+	}
+
+	leaf, err := parseCertificate([]byte(*getOutput.Certificate))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Usages can only be checked when they selected the template; a template
+	// set on the issuer overrides the usages of the request.
+	checkUsages := cr.GetAnnotations()[TemplateArnAnnotation] == buildTemplateArn(p.arn, cr.Spec, "")
+	if err := verifyIssuedCertificate(cr, leaf, chainIntCAs, rootCA, checkUsages); err != nil {
+		return nil, nil, err
+	}
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, SerialNumberAnnotation, formatSerialNumber(leaf.SerialNumber))
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, NotBeforeAnnotation, leaf.NotBefore.UTC().Format(time.RFC3339))
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, NotAfterAnnotation, leaf.NotAfter.UTC().Format(time.RFC3339))
 
 	log.Info("Created certificate with arn: " + certArn)
 
-	return certPem, rootCA, nil
+	return certPem, caPem, nil
+}
+
+// GetCACertificate returns the PEM encoded certificate of the CA followed by
+// its chain, if the CA is subordinate.
+func (p *PCAProvisioner) GetCACertificate(ctx context.Context) ([]byte, error) {
+	output, err := p.pcaClient.GetCertificateAuthorityCertificate(ctx, &acmpca.GetCertificateAuthorityCertificateInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := []byte(aws.ToString(output.Certificate) + "\n")
+	if output.CertificateChain != nil {
+		bundle = append(bundle, []byte(aws.ToString(output.CertificateChain)+"\n")...)
+	}
+	return bundle, nil
 }
 
 func getSigningAlgorithm(ctx context.Context, p *PCAProvisioner) error {
@@ -287,57 +484,130 @@ func (p *PCAProvisioner) now() time.Time {
 
 func buildTemplateArn(caArn string, spec cmapi.CertificateRequestSpec, templateName string) string {
 	parsedArn, _ := arn.Parse(caArn)
-	prefix := "arn:" + parsedArn.Partition + ":acm-pca:::template/"
+	return "arn:" + parsedArn.Partition + ":acm-pca:::template/" + buildTemplateName(spec, templateName)
+}
 
+// TemplateName returns the name of the PCA template Sign uses for a
+// CertificateRequest, given the default template name of its issuer
+func TemplateName(spec cmapi.CertificateRequestSpec, templateName string) string {
+	if templateName == "" {
+		templateName = GetDefaults().TemplateName
+	}
+	return buildTemplateName(spec, templateName)
+}
+
+func buildTemplateName(spec cmapi.CertificateRequestSpec, templateName string) string {
 	if templateName != "" {
-		return prefix + templateName
+		return templateName
 	}
 
 	if spec.IsCA {
-		return prefix + "SubordinateCACertificate_PathLen0/V1"
+		return "SubordinateCACertificate_PathLen0/V1"
 	}
 
 	if len(spec.Usages) == 1 {
 		switch spec.Usages[0] {
 		case cmapi.UsageCodeSigning:
-			return prefix + "CodeSigningCertificate/V1"
+			return "CodeSigningCertificate/V1"
 		case cmapi.UsageClientAuth:
-			return prefix + "EndEntityClientAuthCertificate/V1"
+			return "EndEntityClientAuthCertificate/V1"
 		case cmapi.UsageServerAuth:
-			return prefix + "EndEntityServerAuthCertificate/V1"
+			return "EndEntityServerAuthCertificate/V1"
 		case cmapi.UsageOCSPSigning:
-			return prefix + "OCSPSigningCertificate/V1"
+			return "OCSPSigningCertificate/V1"
 		}
 	} else if len(spec.Usages) == 2 {
 		clientServer := (spec.Usages[0] == cmapi.UsageClientAuth && spec.Usages[1] == cmapi.UsageServerAuth)
 		serverClient := (spec.Usages[0] == cmapi.UsageServerAuth && spec.Usages[1] == cmapi.UsageClientAuth)
 		if clientServer || serverClient {
-			return prefix + "EndEntityCertificate/V1"
+			return "EndEntityCertificate/V1"
 		}
 	}
 
-	return prefix + "BlankEndEntityCertificate_APICSRPassthrough/V1"
+	return "BlankEndEntityCertificate_APICSRPassthrough/V1"
 }
 
+func parseCertificate(certPem []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPem)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("failed to decode certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// formatSerialNumber renders a serial number the way PCA audit reports and
+// CloudTrail do, as colon separated lowercase hex bytes.
+func formatSerialNumber(serial *big.Int) string {
+	b := serial.Bytes()
+	if len(b) == 0 {
+		b = []byte{0}
+	}
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, ":")
+}
+
+// splitRootCACertificate separates a PEM encoded CA chain into its
+// intermediate certificates and its root certificate. The root is the
+// self-signed certificate in the chain; if the chain holds no self-signed
+// certificate, the last certificate is treated as the root.
 func splitRootCACertificate(caCertChainPem []byte) ([]byte, []byte, error) {
-	var caChainCerts []byte
-	var rootCACert []byte
+	var blocks []*pem.Block
+	var certs []*x509.Certificate
 	for {
 		block, rest := pem.Decode(caCertChainPem)
 		if block == nil || block.Type != "CERTIFICATE" {
 			return nil, nil, fmt.Errorf("failed to read certificate")
 		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, block)
+		certs = append(certs, cert)
+		if len(rest) == 0 {
+			break
+		}
+		caCertChainPem = rest
+	}
+
+	rootIndex := len(certs) - 1
+	for i, cert := range certs {
+		if isSelfSigned(cert) {
+			rootIndex = i
+			break
+		}
+	}
+
+	var caChainCerts []byte
+	var rootCACert []byte
+	for i, block := range blocks {
 		var encBuf bytes.Buffer
 		if err := pem.Encode(&encBuf, block); err != nil {
 			return nil, nil, err
 		}
-		if len(rest) > 0 {
-			caChainCerts = append(caChainCerts, encBuf.Bytes()...)
-			caCertChainPem = rest
+		if i == rootIndex {
+			rootCACert = encBuf.Bytes()
 		} else {
-			rootCACert = append(rootCACert, encBuf.Bytes()...)
-			break
+			caChainCerts = append(caChainCerts, encBuf.Bytes()...)
 		}
 	}
 	return caChainCerts, rootCACert, nil
 }
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// CheckDefaultIdentity calls sts.GetCallerIdentity with the default identity
+// of the controller to check it can reach AWS
+func CheckDefaultIdentity(ctx context.Context) error {
+	cfg, err := GetConfig(ctx, nil, &api.AWSPCAIssuerSpec{})
+	if err != nil {
+		return err
+	}
+	_, err = sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	return err
+}
@@ -16,13 +16,18 @@ package aws
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"math/big"
 	"strings"
 	"testing"
 	"time"
@@ -187,9 +192,15 @@ func (m *errorACMPCAClient) GetCertificate(_ context.Context, input *acmpca.GetC
 	return nil, errors.New("Cannot get certificate")
 }
 
+func (m *errorACMPCAClient) GetCertificateAuthorityCertificate(_ context.Context, input *acmpca.GetCertificateAuthorityCertificateInput, _ ...func(*acmpca.Options)) (*acmpca.GetCertificateAuthorityCertificateOutput, error) {
+	return nil, errors.New("Cannot get certificate authority certificate")
+}
+
 type workingACMPCAClient struct {
 	acmPCAClient
-	issueCertInput *acmpca.IssueCertificateInput
+	issueCertInput   *acmpca.IssueCertificateInput
+	certificate      string
+	certificateChain string
 }
 
 func (m *workingACMPCAClient) DescribeCertificateAuthority(_ context.Context, input *acmpca.DescribeCertificateAuthorityInput, _ ...func(*acmpca.Options)) (*acmpca.DescribeCertificateAuthorityOutput, error) {
@@ -208,10 +219,14 @@ func (m *workingACMPCAClient) IssueCertificate(_ context.Context, input *acmpca.
 }
 
 func (m *workingACMPCAClient) GetCertificate(_ context.Context, input *acmpca.GetCertificateInput, _ ...func(*acmpca.Options)) (*acmpca.GetCertificateOutput, error) {
+	if m.certificate != "" {
+		return &acmpca.GetCertificateOutput{Certificate: &m.certificate, CertificateChain: &m.certificateChain}, nil
+	}
 	return &acmpca.GetCertificateOutput{Certificate: &cert, CertificateChain: &chain}, nil
 }
 
 func TestProvisonerOperation(t *testing.T) {
+	t.Cleanup(issuances.Clear)
 	scheme := runtime.NewScheme()
 	require.NoError(t, issuerapi.AddToScheme(scheme))
 	require.NoError(t, cmapi.AddToScheme(scheme))
@@ -259,6 +274,115 @@ func TestProvisonerOperation(t *testing.T) {
 	assert.Equal(t, err, nil)
 }
 
+func TestRefreshProvisioner(t *testing.T) {
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+	t.Cleanup(ClearProvisioners)
+	SetPollers(startPollers(t))
+	t.Cleanup(func() { SetPollers(nil) })
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+			"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	spec := &issuerapi.AWSPCAIssuerSpec{
+		SecretRef: issuerapi.AWSCredentialsSecretReference{
+			SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+		},
+		Region: "us-east-1",
+		Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+	}
+	provisioner := func() *PCAProvisioner {
+		p, err := GetProvisioner(context.TODO(), fakeClient, name, spec)
+		require.NoError(t, err)
+		return p.(*PCAProvisioner)
+	}
+	refresh := func(generation int64) {
+		version, err := ConfigVersion(context.TODO(), fakeClient, generation, spec)
+		require.NoError(t, err)
+		RefreshProvisioner(name, version)
+	}
+
+	ClearProvisioners()
+	refresh(1)
+	first := provisioner()
+
+	// The provisioner is kept while neither the issuer nor its credentials change
+	refresh(1)
+	assert.Same(t, first, provisioner())
+
+	// A change of the issuer creates a new provisioner, which takes over the
+	// poller of the CA
+	refresh(2)
+	second := provisioner()
+	assert.NotSame(t, first, second)
+	assert.Same(t, first.poller, second.poller)
+	assert.Same(t, second.pcaClient, second.poller.client)
+
+	// So does a change of its credentials
+	secret.Data["AWS_SECRET_ACCESS_KEY"] = []byte("cm90YXRlZA==")
+	require.NoError(t, fakeClient.Update(context.TODO(), secret))
+	refresh(2)
+	third := provisioner()
+	assert.NotSame(t, second, third)
+	assert.Same(t, first.poller, third.poller)
+
+	// The poller is replaced when the issuer moves to another CA
+	_, err := first.poller.get(&cmapi.CertificateRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cr1"}}, "arn-1")
+	assert.ErrorIs(t, err, ErrCertificatePending)
+	spec.Arn = "arn:aws:acm-pca:us-east-1:account:certificate-authority/22345678-1234-1234-1234-123456789012"
+	refresh(3)
+	assert.NotSame(t, first.poller, provisioner().poller)
+	first.poller.mu.Lock()
+	assert.Empty(t, first.poller.pending, "the previous poller should be stopped")
+	first.poller.mu.Unlock()
+	awaitStopped(t, first.poller)
+}
+
+func TestSetDefaultsEvictsProvisioners(t *testing.T) {
+	t.Cleanup(ClearProvisioners)
+	SetPollers(startPollers(t))
+	t.Cleanup(func() { SetPollers(nil) })
+	SetDefaults(Defaults{Region: "us-east-1"})
+	t.Cleanup(func() { SetDefaults(Defaults{}) })
+
+	name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	spec := &issuerapi.AWSPCAIssuerSpec{
+		Arn: "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+	}
+	provisioner := func() *PCAProvisioner {
+		p, err := GetProvisioner(context.TODO(), nil, name, spec)
+		require.NoError(t, err)
+		return p.(*PCAProvisioner)
+	}
+	region := func(p *PCAProvisioner) string {
+		return p.pcaClient.(*acmpca.Client).Options().Region
+	}
+
+	first := provisioner()
+	assert.Equal(t, "us-east-1", region(first))
+
+	// Other defaults keep the provisioner
+	SetDefaults(Defaults{Region: "us-east-1", TemplateName: "EndEntityCertificate/V1"})
+	assert.Same(t, first, provisioner())
+
+	// A new region creates a new provisioner, which takes over the poller
+	SetDefaults(Defaults{Region: "eu-west-1"})
+	second := provisioner()
+	assert.NotSame(t, first, second)
+	assert.Equal(t, "eu-west-1", region(second))
+	assert.Same(t, first.poller, second.poller)
+	assert.Same(t, second.pcaClient, second.poller.client)
+}
+
 func createPCATemplateTestCase(expectedTemplateName string, usages []cmapi.KeyUsage, isCA bool, pcaTemplateName string) pcaTemplateTestCase {
 	tc := pcaTemplateTestCase{
 		expectedTemplateArn: ":acm-pca:::template/" + expectedTemplateName,
@@ -324,27 +448,59 @@ func TestIdempotencyToken(t *testing.T) {
 	)
 
 	type testCase struct {
+		strategy IdempotencyTokenStrategy
 		request  cmapi.CertificateRequest
 		expected string
 	}
 
-	tests := map[string]testCase{
-		"success": {
-			request: cmapi.CertificateRequest{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fake-name",
-					Namespace: "fake-namespace",
-				},
+	request := func(uid types.UID, csr string) cmapi.CertificateRequest {
+		return cmapi.CertificateRequest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fake-name",
+				Namespace: "fake-namespace",
+				UID:       uid,
 			},
+			Spec: cmapi.CertificateRequestSpec{Request: []byte(csr)},
+		}
+	}
+
+	tests := map[string]testCase{
+		"success-name": {
+			strategy: IdempotencyTokenStrategyName,
+			request:  request("uid-1", "csr-1"),
 			expected: "63e69830270b95081942a3d85034fdc97bb9", // Truncated SHA-256 hash
 		},
+		"success-request": {
+			strategy: IdempotencyTokenStrategyRequest,
+			request:  request("uid-1", "csr-1"),
+			expected: "0f3438070ae818e14c941026c72bc94069ec",
+		},
+		"success-request-recreated": {
+			strategy: IdempotencyTokenStrategyRequest,
+			request:  request("uid-2", "csr-1"),
+			expected: "",
+		},
+		"success-request-new-csr": {
+			strategy: IdempotencyTokenStrategyRequest,
+			request:  request("uid-1", "csr-2"),
+			expected: "",
+		},
 	}
 
+	t.Cleanup(func() { SetIdempotencyTokenStrategy(IdempotencyTokenStrategyRequest) })
+	seen := map[string]string{}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
+			SetIdempotencyTokenStrategy(tc.strategy)
 			token := idempotencyToken(&tc.request)
-			assert.Equal(t, tc.expected, token)
+			if tc.expected != "" {
+				assert.Equal(t, tc.expected, token)
+			}
 			assert.LessOrEqual(t, len(token), idempotencyTokenMaxLength)
+			for other, otherToken := range seen {
+				assert.NotEqual(t, otherToken, token, "token of %s equals token of %s", name, other)
+			}
+			seen[name] = token
 		})
 	}
 }
@@ -680,57 +836,268 @@ func TestPCAGetConfig(t *testing.T) {
 	}
 }
 
+// generateTestChain issues a certificate for pub from a freshly generated
+// root and intermediate CA, returning PEM encoded certificates the way PCA
+// does, without a trailing newline.
+func generateTestChain(t *testing.T, leafTemplate *x509.Certificate, pub crypto.PublicKey) (string, string, string) {
+	t.Helper()
+
+	encode := func(der []byte) string {
+		return strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	}
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDer, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	rootCert, err := x509.ParseCertificate(rootDer)
+	require.NoError(t, err)
+
+	intermediateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	intermediateTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	intermediateDer, err := x509.CreateCertificate(rand.Reader, intermediateTemplate, rootCert, &intermediateKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	intermediateCert, err := x509.ParseCertificate(intermediateDer)
+	require.NoError(t, err)
+
+	leafDer, err := x509.CreateCertificate(rand.Reader, leafTemplate, intermediateCert, pub, intermediateKey)
+	require.NoError(t, err)
+
+	return encode(leafDer), encode(intermediateDer), encode(rootDer)
+}
+
 func TestPCAGet(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(0x1234),
+		Subject:      pkix.Name{CommonName: "domain.com"},
+		DNSNames:     []string{"domain.com"},
+		NotBefore:    time.Date(2021, 5, 20, 21, 55, 20, 0, time.UTC),
+		NotAfter:     time.Date(2021, 8, 18, 21, 55, 20, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	issuedCert, issuedIntermediate, issuedRoot := generateTestChain(t, leafTemplate, &key.PublicKey)
+	_, otherIntermediate, otherRoot := generateTestChain(t, leafTemplate, &key.PublicKey)
+	workingClient := &workingACMPCAClient{certificate: issuedCert, certificateChain: issuedIntermediate + "\n" + issuedRoot}
+
+	clientAuthSpec := cmapi.CertificateRequestSpec{Usages: []cmapi.KeyUsage{cmapi.UsageClientAuth}}
+
 	type testCase struct {
-		provisioner   PCAProvisioner
-		expectFailure bool
-		expectedChain string
-		expectedCert  string
+		provisioner    PCAProvisioner
+		csrKey         *rsa.PrivateKey
+		dnsNames       []string
+		usages         []cmapi.KeyUsage
+		annotations    map[string]string
+		token          IdempotencyTokenStrategy
+		startedAfter   time.Duration
+		expectFailure  bool
+		expectedError  error
+		expectedChain  string
+		expectedCert   string
+		expectedSerial string
 	}
 
 	tests := map[string]testCase{
 		"success": {
-			provisioner:   PCAProvisioner{arn: caArn, pcaClient: &workingACMPCAClient{}},
+			provisioner:    PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			dnsNames:       []string{"domain.com"},
+			expectFailure:  false,
+			expectedChain:  string([]byte(issuedRoot + "\n")),
+			expectedCert:   string([]byte(issuedCert + "\n" + issuedIntermediate + "\n")),
+			expectedSerial: "12:34",
+		},
+		"success-leaf-only": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: workingClient, chain: issuerapi.CertificateChain{
+				Certificate: issuerapi.CertificateChainLayoutLeaf,
+			}},
+			expectFailure: false,
+			expectedChain: string([]byte(issuedRoot + "\n")),
+			expectedCert:  string([]byte(issuedCert + "\n")),
+		},
+		"success-full-chain": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: workingClient, chain: issuerapi.CertificateChain{
+				Certificate: issuerapi.CertificateChainLayoutFullChain,
+				CA:          issuerapi.CALayoutChain,
+			}},
+			expectFailure: false,
+			expectedChain: string([]byte(issuedIntermediate + "\n" + issuedRoot + "\n")),
+			expectedCert:  string([]byte(issuedCert + "\n" + issuedIntermediate + "\n" + issuedRoot + "\n")),
+		},
+		"success-root-detected-by-self-signature": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: &workingACMPCAClient{
+				certificate:      issuedCert,
+				certificateChain: issuedRoot + "\n" + issuedIntermediate,
+			}},
+			expectFailure: false,
+			expectedChain: string([]byte(issuedRoot + "\n")),
+			expectedCert:  string([]byte(issuedCert + "\n" + issuedIntermediate + "\n")),
+		},
+		"success-usages-overridden-by-issuer-template": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			usages:      clientAuthSpec.Usages,
+			annotations: map[string]string{
+				TemplateArnAnnotation: buildTemplateArn(caArn, clientAuthSpec, "EndEntityServerAuthCertificate/V1"),
+			},
+			expectFailure: false,
+		},
+		"success-requested-validity": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			annotations: map[string]string{
+				RequestedNotAfterAnnotation: "2021-08-18T21:55:00Z",
+			},
 			expectFailure: false,
-			expectedChain: string([]byte(root + "\n")),
-			expectedCert:  string([]byte(cert + "\n" + intermediate + "\n")),
 		},
 		"failure-error-getCertificate": {
 			provisioner:   PCAProvisioner{arn: caArn, pcaClient: &errorACMPCAClient{}},
 			expectFailure: true,
 		},
+		"failure-public-key-mismatch": {
+			provisioner:   PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			csrKey:        otherKey,
+			expectFailure: true,
+			expectedError: ErrCertificateMismatch,
+		},
+		"failure-public-key-mismatch-name-token-reused": {
+			provisioner:   PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			csrKey:        otherKey,
+			token:         IdempotencyTokenStrategyName,
+			startedAfter:  time.Minute,
+			expectFailure: true,
+			expectedError: ErrIdempotencyMismatch,
+		},
+		"failure-public-key-mismatch-name-token-after-window": {
+			provisioner:   PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			csrKey:        otherKey,
+			token:         IdempotencyTokenStrategyName,
+			startedAfter:  IdempotencyWindow + time.Minute,
+			expectFailure: true,
+			expectedError: ErrCertificateMismatch,
+		},
+		"failure-public-key-mismatch-request-token": {
+			provisioner:   PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			csrKey:        otherKey,
+			token:         IdempotencyTokenStrategyRequest,
+			startedAfter:  time.Minute,
+			expectFailure: true,
+			expectedError: ErrCertificateMismatch,
+		},
+		"failure-untrusted-chain": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: &workingACMPCAClient{
+				certificate:      issuedCert,
+				certificateChain: otherIntermediate + "\n" + otherRoot,
+			}},
+			expectFailure: true,
+			expectedError: ErrCertificateMismatch,
+		},
+		"failure-missing-dns-name": {
+			provisioner:   PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			dnsNames:      []string{"domain.com", "other.domain.com"},
+			expectFailure: true,
+			expectedError: ErrCertificateMismatch,
+		},
+		"failure-missing-usage": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			usages:      clientAuthSpec.Usages,
+			annotations: map[string]string{
+				TemplateArnAnnotation: buildTemplateArn(caArn, clientAuthSpec, ""),
+			},
+			expectFailure: true,
+			expectedError: ErrCertificateMismatch,
+		},
+		"failure-validity-mismatch": {
+			provisioner: PCAProvisioner{arn: caArn, pcaClient: workingClient},
+			annotations: map[string]string{
+				RequestedNotAfterAnnotation: "2021-09-18T21:55:20Z",
+			},
+			expectFailure: true,
+			expectedError: ErrCertificateMismatch,
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			key, _ := rsa.GenerateKey(rand.Reader, 2048)
-			csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &template, key)
+			csrKey := key
+			if tc.csrKey != nil {
+				csrKey = tc.csrKey
+			}
+			csrTemplate := template
+			csrTemplate.DNSNames = tc.dnsNames
+			csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &csrTemplate, csrKey)
 
+			created := time.Date(2021, 5, 20, 21, 55, 0, 0, time.UTC)
 			cr := &cmapi.CertificateRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations:       tc.annotations,
+					CreationTimestamp: metav1.NewTime(created),
+				},
 				Spec: cmapi.CertificateRequestSpec{
 					Request: pem.EncodeToMemory(&pem.Block{
 						Bytes: csrBytes,
 						Type:  "CERTIFICATE REQUEST",
 					}),
+					Usages: tc.usages,
 				},
 			}
+			if tc.token != "" {
+				metav1.SetMetaDataAnnotation(&cr.ObjectMeta, IdempotencyTokenAnnotation, idempotencyTokenFor(cr, tc.token))
+				metav1.SetMetaDataAnnotation(&cr.ObjectMeta, IssuanceStartedAnnotation, created.Add(tc.startedAfter).Format(time.RFC3339))
+			}
 
 			leaf, chain, err := tc.provisioner.Get(context.TODO(), cr, certArn, logr.Discard())
 
 			if tc.expectFailure && err == nil {
-				fmt.Print(err.Error())
 				assert.Fail(t, "Expected an error but received none")
 			}
+			if !tc.expectFailure {
+				assert.NoError(t, err)
+			}
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			}
+			if tc.expectedError == ErrCertificateMismatch {
+				assert.NotErrorIs(t, err, ErrIdempotencyMismatch)
+			}
 
 			if tc.expectedChain != "" && tc.expectedCert != "" {
 				assert.Equal(t, []byte(tc.expectedCert), leaf)
 				assert.Equal(t, []byte(tc.expectedChain), chain)
 			}
+
+			if tc.expectedSerial != "" {
+				annotations := cr.GetAnnotations()
+				assert.Equal(t, tc.expectedSerial, annotations[SerialNumberAnnotation])
+				assert.Equal(t, "2021-05-20T21:55:20Z", annotations[NotBeforeAnnotation])
+				assert.Equal(t, "2021-08-18T21:55:20Z", annotations[NotAfterAnnotation])
+			}
 		})
 	}
 }
 
 func TestPCASign(t *testing.T) {
+	t.Cleanup(issuances.Clear)
 	type testCase struct {
 		provisioner     PCAProvisioner
 		expectFailure   bool
@@ -751,6 +1118,7 @@ func TestPCASign(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
+			issuances.Clear()
 			key, _ := rsa.GenerateKey(rand.Reader, 2048)
 			csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &template, key)
 
@@ -773,11 +1141,68 @@ func TestPCASign(t *testing.T) {
 			if tc.expectedCertArn != "" {
 				assert.Equal(t, cr.ObjectMeta.GetAnnotations()["aws-privateca-issuer/certificate-arn"], tc.expectedCertArn)
 			}
+
+			if tc.expectedCertArn != "" {
+				annotations := cr.ObjectMeta.GetAnnotations()
+				assert.Equal(t, caArn, annotations[CertificateAuthorityArnAnnotation])
+				assert.Equal(t, "arn:aws:acm-pca:::template/BlankEndEntityCertificate_APICSRPassthrough/V1", annotations[TemplateArnAnnotation])
+				assert.Equal(t, string(acmpcatypes.SigningAlgorithmSha256withecdsa), annotations[SigningAlgorithmAnnotation])
+				assert.Equal(t, idempotencyToken(cr), annotations[IdempotencyTokenAnnotation])
+				assert.NotEmpty(t, annotations[RequestedNotAfterAnnotation])
+			}
+		})
+	}
+}
+
+func (m *workingACMPCAClient) GetCertificateAuthorityCertificate(_ context.Context, input *acmpca.GetCertificateAuthorityCertificateInput, _ ...func(*acmpca.Options)) (*acmpca.GetCertificateAuthorityCertificateOutput, error) {
+	return &acmpca.GetCertificateAuthorityCertificateOutput{Certificate: &intermediate, CertificateChain: &root}, nil
+}
+
+type rootCAACMPCAClient struct {
+	acmPCAClient
+}
+
+func (m *rootCAACMPCAClient) GetCertificateAuthorityCertificate(_ context.Context, input *acmpca.GetCertificateAuthorityCertificateInput, _ ...func(*acmpca.Options)) (*acmpca.GetCertificateAuthorityCertificateOutput, error) {
+	return &acmpca.GetCertificateAuthorityCertificateOutput{Certificate: &root}, nil
+}
+
+func TestPCAGetCACertificate(t *testing.T) {
+	type testCase struct {
+		provisioner    PCAProvisioner
+		expectFailure  bool
+		expectedBundle string
+	}
+
+	tests := map[string]testCase{
+		"success-subordinate-ca": {
+			provisioner:    PCAProvisioner{arn: caArn, pcaClient: &workingACMPCAClient{}},
+			expectedBundle: intermediate + "\n" + root + "\n",
+		},
+		"success-root-ca": {
+			provisioner:    PCAProvisioner{arn: caArn, pcaClient: &rootCAACMPCAClient{}},
+			expectedBundle: root + "\n",
+		},
+		"failure-error-getCertificateAuthorityCertificate": {
+			provisioner:   PCAProvisioner{arn: caArn, pcaClient: &errorACMPCAClient{}},
+			expectFailure: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			bundle, err := tc.provisioner.GetCACertificate(context.TODO())
+			if tc.expectFailure {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(tc.expectedBundle), bundle)
 		})
 	}
 }
 
 func TestPCASignValidity(t *testing.T) {
+	t.Cleanup(issuances.Clear)
 	now := time.Now()
 	client := &workingACMPCAClient{}
 	provisioner := PCAProvisioner{arn: caArn, pcaClient: client}
@@ -812,6 +1237,7 @@ func TestPCASignValidity(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
+			issuances.Clear()
 			client.issueCertInput = nil
 			key, _ := rsa.GenerateKey(rand.Reader, 2048)
 			csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &template, key)
@@ -847,3 +1273,114 @@ func ptrInt(i int64) *int64 {
 func ptrDuration(d metav1.Duration) *metav1.Duration {
 	return &d
 }
+
+func TestPCASignDefaults(t *testing.T) {
+	t.Cleanup(issuances.Clear)
+	now := time.Now()
+	client := &workingACMPCAClient{}
+	provisioner := PCAProvisioner{arn: caArn, pcaClient: client, clock: func() time.Time { return now }}
+
+	SetDefaults(Defaults{Duration: 24 * time.Hour, TemplateName: "EndEntityCertificate/V1"})
+	t.Cleanup(func() { SetDefaults(Defaults{}) })
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &template, key)
+	cr := &cmapi.CertificateRequest{
+		Spec: cmapi.CertificateRequestSpec{
+			Request: pem.EncodeToMemory(&pem.Block{
+				Bytes: csrBytes,
+				Type:  "CERTIFICATE REQUEST",
+			}),
+		},
+	}
+
+	require.NoError(t, provisioner.Sign(context.TODO(), cr, "", logr.Discard()))
+	assert.Equal(t, now.Unix()+int64((24*time.Hour).Seconds()), *client.issueCertInput.Validity.Value)
+	assert.Equal(t, "arn:aws:acm-pca:::template/EndEntityCertificate/V1", *client.issueCertInput.TemplateArn)
+
+	issuances.Clear()
+	require.NoError(t, provisioner.Sign(context.TODO(), cr, "EndEntityServerAuthCertificate/V1", logr.Discard()))
+	assert.Equal(t, "arn:aws:acm-pca:::template/EndEntityServerAuthCertificate/V1", *client.issueCertInput.TemplateArn)
+
+	SetDefaults(Defaults{})
+	assert.Equal(t, DEFAULT_DURATION*time.Second, GetDefaults().Duration)
+}
+
+func TestPCADryRun(t *testing.T) {
+	t.Cleanup(issuances.Clear)
+	now := time.Now()
+	client := &workingACMPCAClient{}
+	provisioner := PCAProvisioner{arn: caArn, pcaClient: client, clock: func() time.Time { return now }}
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &template, key)
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cr1", UID: "uid-1"},
+		Spec: cmapi.CertificateRequestSpec{
+			Duration: &metav1.Duration{Duration: time.Hour},
+			Request:  pem.EncodeToMemory(&pem.Block{Bytes: csrBytes, Type: "CERTIFICATE REQUEST"}),
+		},
+	}
+
+	require.NoError(t, provisioner.DryRun(context.TODO(), cr, "EndEntityServerAuthCertificate/V1", logr.Discard()))
+	assert.Nil(t, client.issueCertInput, "no certificate should be issued")
+	assert.Empty(t, cr.GetAnnotations()[CertificateArnAnnotation])
+
+	var input acmpca.IssueCertificateInput
+	require.NoError(t, json.Unmarshal([]byte(cr.GetAnnotations()[DryRunInputAnnotation]), &input))
+	assert.Equal(t, caArn, *input.CertificateAuthorityArn)
+	assert.Equal(t, "arn:aws:acm-pca:::template/EndEntityServerAuthCertificate/V1", *input.TemplateArn)
+	assert.Equal(t, now.Unix()+3600, *input.Validity.Value)
+	assert.Equal(t, idempotencyToken(cr), *input.IdempotencyToken)
+	assert.Empty(t, input.Csr)
+
+	cr.Spec.Request = []byte("not a csr")
+	assert.Error(t, provisioner.DryRun(context.TODO(), cr, "", logr.Discard()))
+}
+
+func TestPCASignRecoversIssuance(t *testing.T) {
+	t.Cleanup(issuances.Clear)
+	now := time.Now()
+	client := &countingACMPCAClient{}
+	provisioner := PCAProvisioner{arn: caArn, pcaClient: client, clock: func() time.Time { return now }}
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &template, key)
+	newRequest := func() *cmapi.CertificateRequest {
+		return &cmapi.CertificateRequest{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cr1", UID: "uid-1"},
+			Spec: cmapi.CertificateRequestSpec{
+				Request: pem.EncodeToMemory(&pem.Block{Bytes: csrBytes, Type: "CERTIFICATE REQUEST"}),
+			},
+		}
+	}
+
+	// The ARN annotation of the first attempt was lost
+	require.NoError(t, provisioner.Sign(context.TODO(), newRequest(), "", logr.Discard()))
+	cr := newRequest()
+	require.NoError(t, provisioner.Sign(context.TODO(), cr, "", logr.Discard()))
+	assert.Equal(t, 1, client.issued, "certificate should only be issued once")
+	assert.Equal(t, "arn-1", cr.GetAnnotations()[CertificateArnAnnotation])
+
+	// The remembered ARN expires
+	now = now.Add(issuanceTTL + time.Minute)
+	require.NoError(t, provisioner.Sign(context.TODO(), cr, "", logr.Discard()))
+	assert.Equal(t, 2, client.issued)
+	assert.Equal(t, "arn-2", cr.GetAnnotations()[CertificateArnAnnotation])
+
+	// Another request is issued its own certificate
+	other := newRequest()
+	other.UID = "uid-2"
+	require.NoError(t, provisioner.Sign(context.TODO(), other, "", logr.Discard()))
+	assert.Equal(t, 3, client.issued)
+}
+
+type countingACMPCAClient struct {
+	workingACMPCAClient
+	issued int
+}
+
+func (m *countingACMPCAClient) IssueCertificate(_ context.Context, input *acmpca.IssueCertificateInput, _ ...func(*acmpca.Options)) (*acmpca.IssueCertificateOutput, error) {
+	m.issued++
+	return &acmpca.IssueCertificateOutput{CertificateArn: aws.String(fmt.Sprintf("arn-%d", m.issued))}, nil
+}
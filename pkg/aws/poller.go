@@ -0,0 +1,413 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// ErrCertificatePending is returned by Get while a certificate is queued for
+// polling. The CertificateRequest is sent on Pollers.Completed once the
+// certificate was retrieved, or retrieving it failed.
+var ErrCertificatePending = errors.New("certificate is still being issued")
+
+var (
+	// pollInterval is how long the poller of a CA waits between rounds of
+	// GetCertificate calls
+	pollInterval = 2 * time.Second
+	// pollTimeout bounds each GetCertificate call of a poller
+	pollTimeout = 30 * time.Second
+	// polledResultTTL is how long the result of a poll is kept for a
+	// CertificateRequest that is not reconciled again, for example because
+	// it was deleted
+	polledResultTTL = 10 * time.Minute
+)
+
+// activePollers is the poller set provisioners queue their certificates on.
+// Without one, Get calls GetCertificate itself.
+var activePollers atomic.Pointer[Pollers]
+
+// SetPollers sets the poller set the provisioners created from now on queue
+// their certificates on
+func SetPollers(s *Pollers) {
+	activePollers.Store(s)
+}
+
+// Pollers runs the pollers of the CAs of the issuers. It is added to the
+// manager, so that the pollers stop, and their calls to PCA are cancelled,
+// when the manager does. The CertificateRequests whose certificate has been
+// polled are sent on Completed.
+type Pollers struct {
+	// Owns, if set, reports whether this replica still handles a
+	// CertificateRequest. The certificates of the others are dropped from
+	// the queues, for example after losing their shard.
+	Owns func(types.NamespacedName) bool
+
+	completed chan event.GenericEvent
+	wake      chan struct{}
+	// ctx holds the context of the manager once the set was started
+	ctx atomic.Value
+
+	mu       sync.Mutex
+	byIssuer map[types.NamespacedName]*poller
+	// done holds the CertificateRequests that are still to be sent on
+	// completed. A CertificateRequest completed again before it was sent is
+	// only sent once.
+	done map[types.NamespacedName]struct{}
+}
+
+// NewPollers returns a poller set that polls once it is started
+func NewPollers() *Pollers {
+	return &Pollers{
+		completed: make(chan event.GenericEvent),
+		wake:      make(chan struct{}, 1),
+		byIssuer:  map[types.NamespacedName]*poller{},
+		done:      map[types.NamespacedName]struct{}{},
+	}
+}
+
+// Completed returns the channel the CertificateRequests whose certificate has
+// been polled are sent on, to be reconciled again
+func (s *Pollers) Completed() <-chan event.GenericEvent {
+	return s.completed
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Pollers run
+// wherever certificates are signed, which is every replica with sharding.
+func (s *Pollers) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable. It starts the pollers with queued
+// certificates, and sends the completed CertificateRequests until ctx is
+// done.
+func (s *Pollers) Start(ctx context.Context) error {
+	s.ctx.Store(ctx)
+	s.mu.Lock()
+	pollers := make([]*poller, 0, len(s.byIssuer))
+	for _, q := range s.byIssuer {
+		pollers = append(pollers, q)
+	}
+	s.mu.Unlock()
+	for _, q := range pollers {
+		q.start(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.wake:
+		}
+
+		s.mu.Lock()
+		done := make([]types.NamespacedName, 0, len(s.done))
+		for key := range s.done {
+			done = append(done, key)
+		}
+		clear(s.done)
+		s.mu.Unlock()
+
+		for _, key := range done {
+			select {
+			case s.completed <- event.GenericEvent{Object: &cmapi.CertificateRequest{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			}}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// context returns the context of the manager, or nil if the set was not
+// started yet
+func (s *Pollers) context() context.Context {
+	ctx, _ := s.ctx.Load().(context.Context)
+	return ctx
+}
+
+// complete queues a CertificateRequest to be sent on Completed
+func (s *Pollers) complete(key types.NamespacedName) {
+	s.mu.Lock()
+	s.done[key] = struct{}{}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// owns reports whether the certificate of a CertificateRequest is polled by
+// this replica
+func (s *Pollers) owns(key types.NamespacedName) bool {
+	return s.Owns == nil || s.Owns(key)
+}
+
+// pollerFor returns the poller of the CA of an issuer. The poller of a
+// previous provisioner of the issuer is taken over when it polls the same CA,
+// so that the certificates it has queued or polled are not lost, and stopped
+// otherwise.
+func (s *Pollers) pollerFor(name types.NamespacedName, provisioner *PCAProvisioner) *poller {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.byIssuer[name]; ok {
+		if q.arn == provisioner.arn {
+			q.setClient(provisioner.pcaClient)
+			return q
+		}
+		q.stop()
+	}
+	q := newPoller(s, provisioner.pcaClient, provisioner.arn)
+	s.byIssuer[name] = q
+	return q
+}
+
+// remove stops and forgets the poller of an issuer
+func (s *Pollers) remove(name types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.byIssuer[name]; ok {
+		q.stop()
+		delete(s.byIssuer, name)
+	}
+}
+
+// clear stops and forgets the pollers of all issuers
+func (s *Pollers) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range s.byIssuer {
+		q.stop()
+	}
+	clear(s.byIssuer)
+}
+
+// poller retrieves the certificates issued by one CA. Rather than every
+// CertificateRequest waiting on its own GetCertificate calls, outstanding
+// certificate ARNs are queued and a single worker polls them in rounds, so
+// the load on PCA is bounded however many certificates are issued at once.
+// The worker stops when the queue is empty.
+type poller struct {
+	set    *Pollers
+	client acmPCAClient
+	arn    string
+	now    func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	pending map[string]pendingCertificate
+	results map[string]polledCertificate
+}
+
+type pendingCertificate struct {
+	namespace string
+	name      string
+	optFns    []func(*acmpca.Options)
+}
+
+func (p pendingCertificate) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: p.namespace, Name: p.name}
+}
+
+type polledCertificate struct {
+	output   *acmpca.GetCertificateOutput
+	err      error
+	polledAt time.Time
+}
+
+func newPoller(set *Pollers, client acmPCAClient, arn string) *poller {
+	return &poller{
+		set:     set,
+		client:  client,
+		arn:     arn,
+		now:     time.Now,
+		pending: map[string]pendingCertificate{},
+		results: map[string]polledCertificate{},
+	}
+}
+
+// get returns the result of polling certArn. If there is none yet, certArn is
+// queued, to be polled with optFns, and ErrCertificatePending returned. The queue is only polled once
+// the poller set was started.
+func (q *poller) get(cr *cmapi.CertificateRequest, certArn string, optFns ...func(*acmpca.Options)) (*acmpca.GetCertificateOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	for arn, result := range q.results {
+		if now.Sub(result.polledAt) > polledResultTTL {
+			delete(q.results, arn)
+		}
+	}
+
+	if result, ok := q.results[certArn]; ok {
+		delete(q.results, certArn)
+		return result.output, result.err
+	}
+
+	if _, ok := q.pending[certArn]; !ok {
+		q.pending[certArn] = pendingCertificate{
+			namespace: cr.Namespace,
+			name:      cr.Name,
+			optFns:    append(optFns, withoutRetries),
+		}
+	}
+	// Read under the lock, so that a set started meanwhile either sees the
+	// certificate queued or is seen here
+	if ctx := q.set.context(); ctx != nil && !q.running {
+		q.running = true
+		go q.run(ctx)
+	}
+	return nil, ErrCertificatePending
+}
+
+// start starts the worker if certificates were queued before the poller set
+// was started
+func (q *poller) start(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) > 0 && !q.running {
+		q.running = true
+		go q.run(ctx)
+	}
+}
+
+// setClient replaces the client of the poller with the one of a new
+// provisioner for the same CA
+func (q *poller) setClient(client acmPCAClient) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.client = client
+}
+
+// stop drops the queued certificates, so that the worker stops after its
+// current round. Their CertificateRequests are queued again when they are
+// requeued.
+func (q *poller) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	clear(q.pending)
+	clear(q.results)
+}
+
+// withoutRetries leaves retrying to the next round of the poller, instead of
+// holding up the other certificates of the round
+func withoutRetries(o *acmpca.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+// retryable reports whether the standard retryer of the SDK would have
+// retried err, or the call ran into pollTimeout. The certificate stays queued
+// and is polled again in the next round, rather than failing the
+// CertificateRequest.
+func retryable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// run polls the queue every pollInterval until it is empty or ctx is done
+func (q *poller) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+			q.poll(ctx)
+		}
+
+		q.mu.Lock()
+		if len(q.pending) == 0 || ctx.Err() != nil {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+	}
+}
+
+// poll calls GetCertificate once for every queued certificate. The round is
+// cut short when PCA throttles the poller or ctx is done. Certificates that
+// are still being issued, or whose call failed with a retryable error, stay
+// queued, while those of CertificateRequests this replica no longer handles
+// are dropped.
+func (q *poller) poll(ctx context.Context) {
+	q.mu.Lock()
+	client := q.client
+	pending := make(map[string]pendingCertificate, len(q.pending))
+	for arn, request := range q.pending {
+		if !q.set.owns(request.key()) {
+			delete(q.pending, arn)
+			continue
+		}
+		pending[arn] = request
+	}
+	q.mu.Unlock()
+
+	for certArn, request := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		callCtx, cancel := context.WithTimeout(ctx, pollTimeout)
+		output, err := client.GetCertificate(callCtx, &acmpca.GetCertificateInput{
+			CertificateArn:          aws.String(certArn),
+			CertificateAuthorityArn: aws.String(q.arn),
+		}, request.optFns...)
+		cancel()
+
+		var inProgress *acmpcatypes.RequestInProgressException
+		if errors.As(err, &inProgress) {
+			continue
+		}
+		if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+			return
+		}
+		if retryable(err) || ctx.Err() != nil {
+			continue
+		}
+
+		q.mu.Lock()
+		if _, ok := q.pending[certArn]; !ok {
+			// The poller was stopped during the round
+			q.mu.Unlock()
+			continue
+		}
+		delete(q.pending, certArn)
+		q.results[certArn] = polledCertificate{output: output, err: err, polledAt: q.now()}
+		q.mu.Unlock()
+
+		q.set.complete(request.key())
+	}
+}
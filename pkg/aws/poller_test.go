@@ -0,0 +1,280 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// pollingACMPCAClient returns the errors of a certificate ARN, one per
+// GetCertificate call, before returning its certificate
+type pollingACMPCAClient struct {
+	acmPCAClient
+	mu     sync.Mutex
+	errors map[string][]error
+	calls  map[string]int
+}
+
+func (m *pollingACMPCAClient) GetCertificate(_ context.Context, input *acmpca.GetCertificateInput, _ ...func(*acmpca.Options)) (*acmpca.GetCertificateOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	arn := *input.CertificateArn
+	m.calls[arn]++
+	if errs := m.errors[arn]; len(errs) > 0 {
+		m.errors[arn] = errs[1:]
+		return nil, errs[0]
+	}
+	return &acmpca.GetCertificateOutput{Certificate: &cert, CertificateChain: &chain}, nil
+}
+
+func (m *pollingACMPCAClient) callsFor(arn string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[arn]
+}
+
+// startPollers starts a poller set until the end of the test
+func startPollers(t *testing.T) *Pollers {
+	t.Helper()
+	s := NewPollers()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Start(ctx) }()
+	require.Eventually(t, func() bool { return s.context() != nil }, 5*time.Second, time.Millisecond)
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return s
+}
+
+func awaitPolled(t *testing.T, s *Pollers, names ...string) {
+	t.Helper()
+	remaining := map[string]bool{}
+	for _, name := range names {
+		remaining[name] = true
+	}
+	timeout := time.After(5 * time.Second)
+	for len(remaining) > 0 {
+		select {
+		case e := <-s.Completed():
+			assert.Equal(t, "ns1", e.Object.GetNamespace())
+			delete(remaining, e.Object.GetName())
+		case <-timeout:
+			require.Fail(t, "certificates were not polled", "%v", remaining)
+		}
+	}
+}
+
+func awaitStopped(t *testing.T, q *poller) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return !q.running
+	}, 5*time.Second, time.Millisecond, "poller should stop once its queue is empty")
+}
+
+func TestPoller(t *testing.T) {
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+
+	inProgress := &acmpcatypes.RequestInProgressException{}
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	client := &pollingACMPCAClient{
+		errors: map[string][]error{
+			"arn-1": {inProgress, inProgress},
+			"arn-2": {throttled},
+			"arn-3": {errors.New("certificate failed")},
+			"arn-4": {
+				&smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}},
+					Err:      errors.New("service unavailable"),
+				},
+				&smithy.GenericAPIError{Code: "RequestTimeout"},
+				fmt.Errorf("operation error: %w", context.DeadlineExceeded),
+			},
+		},
+		calls: map[string]int{},
+	}
+	q := newPoller(startPollers(t), client, caArn)
+	request := func(name string) *cmapi.CertificateRequest {
+		return &cmapi.CertificateRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name}}
+	}
+
+	for _, name := range []string{"cr1", "cr2", "cr3", "cr4"} {
+		_, err := q.get(request(name), "arn-"+name[2:])
+		assert.ErrorIs(t, err, ErrCertificatePending)
+	}
+	// Queuing a certificate again does not poll it twice as often
+	_, err := q.get(request("cr1"), "arn-1")
+	assert.ErrorIs(t, err, ErrCertificatePending)
+
+	awaitPolled(t, q.set, "cr1", "cr2", "cr3", "cr4")
+	assert.Equal(t, 3, client.callsFor("arn-1"))
+	assert.Equal(t, 2, client.callsFor("arn-2"))
+	assert.Equal(t, 1, client.callsFor("arn-3"))
+	// Transient errors are retried in the next rounds
+	assert.Equal(t, 4, client.callsFor("arn-4"))
+
+	for _, arn := range []string{"arn-1", "arn-2", "arn-4"} {
+		output, err := q.get(request("cr"), arn)
+		require.NoError(t, err)
+		assert.Equal(t, cert, *output.Certificate)
+	}
+	_, err = q.get(request("cr3"), "arn-3")
+	assert.EqualError(t, err, "certificate failed")
+
+	awaitStopped(t, q)
+
+	// A result is consumed by the first Get, a later one polls again
+	_, err = q.get(request("cr1"), "arn-1")
+	assert.ErrorIs(t, err, ErrCertificatePending)
+	awaitPolled(t, q.set, "cr1")
+	assert.Equal(t, 4, client.callsFor("arn-1"))
+	awaitStopped(t, q)
+}
+
+func TestPollerForgetsStaleResults(t *testing.T) {
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+
+	now := time.Now()
+	client := &pollingACMPCAClient{calls: map[string]int{}}
+	q := newPoller(startPollers(t), client, caArn)
+	q.now = func() time.Time { return now }
+	cr := &cmapi.CertificateRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cr1"}}
+
+	_, err := q.get(cr, "arn-1")
+	assert.ErrorIs(t, err, ErrCertificatePending)
+	awaitPolled(t, q.set, "cr1")
+
+	q.mu.Lock()
+	now = now.Add(polledResultTTL + time.Minute)
+	q.mu.Unlock()
+	_, err = q.get(cr, "arn-1")
+	assert.ErrorIs(t, err, ErrCertificatePending)
+	awaitPolled(t, q.set, "cr1")
+	assert.Equal(t, 2, client.callsFor("arn-1"))
+	awaitStopped(t, q)
+}
+
+func TestPollerStopsWithManager(t *testing.T) {
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+
+	inProgress := &acmpcatypes.RequestInProgressException{}
+	client := &pollingACMPCAClient{
+		errors: map[string][]error{"arn-1": {inProgress, inProgress, inProgress, inProgress, inProgress}},
+		calls:  map[string]int{},
+	}
+	s := NewPollers()
+	q := newPoller(s, client, caArn)
+	cr := &cmapi.CertificateRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cr1"}}
+
+	// Certificates queued before the manager starts are polled once it does
+	_, err := q.get(cr, "arn-1")
+	assert.ErrorIs(t, err, ErrCertificatePending)
+	s.byIssuer[types.NamespacedName{Namespace: "ns1", Name: "issuer1"}] = q
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, client.callsFor("arn-1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Start(ctx) }()
+	assert.Eventually(t, func() bool { return client.callsFor("arn-1") > 0 }, 5*time.Second, time.Millisecond)
+
+	// The worker stops with the manager, although the certificate is pending
+	cancel()
+	assert.NoError(t, <-done)
+	awaitStopped(t, q)
+	calls := client.callsFor("arn-1")
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, calls, client.callsFor("arn-1"))
+}
+
+func TestPollersCoalesceCompletions(t *testing.T) {
+	s := startPollers(t)
+	cr1 := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+	cr2 := types.NamespacedName{Namespace: "ns1", Name: "cr2"}
+
+	// Completions are not dropped while nobody receives them, and those of
+	// the same CertificateRequest are sent once
+	for range 3 {
+		s.complete(cr1)
+	}
+	s.complete(cr2)
+
+	received := map[string]int{}
+	for len(received) < 2 {
+		select {
+		case e := <-s.Completed():
+			received[e.Object.GetName()]++
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "completions were dropped", "%v", received)
+		}
+	}
+	select {
+	case e := <-s.Completed():
+		received[e.Object.GetName()]++
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.LessOrEqual(t, received["cr1"], 2)
+	assert.Equal(t, 1, received["cr2"])
+}
+
+func TestPollerDropsCertificatesNoLongerOwned(t *testing.T) {
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+
+	inProgress := &acmpcatypes.RequestInProgressException{}
+	client := &pollingACMPCAClient{
+		errors: map[string][]error{"arn-1": {inProgress}, "arn-2": {inProgress}},
+		calls:  map[string]int{},
+	}
+	s := startPollers(t)
+	s.Owns = func(key types.NamespacedName) bool { return key.Name != "cr2" }
+	q := newPoller(s, client, caArn)
+
+	for _, name := range []string{"cr1", "cr2"} {
+		_, err := q.get(&cmapi.CertificateRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name}}, "arn-"+name[2:])
+		assert.ErrorIs(t, err, ErrCertificatePending)
+	}
+	awaitPolled(t, s, "cr1")
+	awaitStopped(t, q)
+	assert.Equal(t, 0, client.callsFor("arn-2"))
+}
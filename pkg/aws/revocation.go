@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+// defaultCRLPath is the path of the CRLs in the S3 bucket of a CA without a
+// custom path
+const defaultCRLPath = "crl"
+
+// revocationStatus describes the revocation configuration of the CA with
+// the given ARN, including the CRL and OCSP URLs it writes to certificates
+func revocationStatus(caArn string, config *acmpcatypes.RevocationConfiguration) api.RevocationStatus {
+	var status api.RevocationStatus
+	if config == nil {
+		return status
+	}
+
+	region, domain, id := "", "amazonaws.com", caArn
+	if parsed, err := arn.Parse(caArn); err == nil {
+		region = parsed.Region
+		id = strings.TrimPrefix(parsed.Resource, "certificate-authority/")
+		if strings.HasPrefix(parsed.Partition, "aws-cn") {
+			domain = "amazonaws.com.cn"
+		}
+	}
+
+	if crl := config.CrlConfiguration; crl != nil && aws.ToBool(crl.Enabled) {
+		status.CRLEnabled = true
+		omitted := crl.CrlDistributionPointExtensionConfiguration != nil &&
+			aws.ToBool(crl.CrlDistributionPointExtensionConfiguration.OmitExtension)
+		if !omitted {
+			host := aws.ToString(crl.CustomCname)
+			if host == "" {
+				host = fmt.Sprintf("%s.s3.%s.%s", aws.ToString(crl.S3BucketName), region, domain)
+			}
+			crlPath := aws.ToString(crl.CustomPath)
+			if crlPath == "" {
+				crlPath = defaultCRLPath
+			}
+			status.CRLDistributionPoint = "http://" + path.Join(host, crlPath, id+".crl")
+		}
+	}
+
+	if ocsp := config.OcspConfiguration; ocsp != nil && aws.ToBool(ocsp.Enabled) {
+		status.OCSPEnabled = true
+		if cname := aws.ToString(ocsp.OcspCustomCname); cname != "" {
+			status.OCSPURL = "http://" + cname
+		} else {
+			status.OCSPURL = fmt.Sprintf("http://ocsp.acm-pca.%s.%s", region, domain)
+		}
+	}
+	return status
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/cert-manager/cert-manager/pkg/util/pki"
+)
+
+// SPIFFETemplateName is the PCA template SVIDs are issued with when the
+// issuer has no default template. It takes the extensions of the
+// certificate from the IssueCertificate call rather than from the CSR.
+const SPIFFETemplateName = "BlankEndEntityCertificate_APIPassthrough/V1"
+
+// ServiceAccountUsernamePrefix prefixes the username of service accounts
+const ServiceAccountUsernamePrefix = "system:serviceaccount:"
+
+// ErrInvalidSVIDRequest is returned when a CertificateRequest to an issuer
+// of SPIFFE SVIDs does not request the SPIFFE ID of its requester
+var ErrInvalidSVIDRequest = errors.New("invalid SVID request")
+
+// SPIFFEID returns the SPIFFE ID a CertificateRequest requests, after
+// checking that it is the only SAN of the CSR and that it identifies the
+// service account that created the CertificateRequest
+func SPIFFEID(trustDomain string, cr *cmapi.CertificateRequest) (string, error) {
+	namespace, serviceAccount, ok := strings.Cut(strings.TrimPrefix(cr.Spec.Username, ServiceAccountUsernamePrefix), ":")
+	if !strings.HasPrefix(cr.Spec.Username, ServiceAccountUsernamePrefix) || !ok {
+		return "", fmt.Errorf("%w: requester %q is not a service account", ErrInvalidSVIDRequest, cr.Spec.Username)
+	}
+	id := fmt.Sprintf("spiffe://%s/ns/%s/sa/%s", trustDomain, namespace, serviceAccount)
+
+	csr, err := pki.DecodeX509CertificateRequestBytes(cr.Spec.Request)
+	if err != nil {
+		return "", err
+	}
+	if len(csr.DNSNames) > 0 || len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 {
+		return "", fmt.Errorf("%w: only a URI SAN may be requested", ErrInvalidSVIDRequest)
+	}
+	if len(csr.URIs) != 1 {
+		return "", fmt.Errorf("%w: exactly one URI SAN must be requested, got %d", ErrInvalidSVIDRequest, len(csr.URIs))
+	}
+	if uri := csr.URIs[0].String(); uri != id {
+		return "", fmt.Errorf("%w: requested URI SAN %s is not %s", ErrInvalidSVIDRequest, uri, id)
+	}
+	if cr.Spec.IsCA {
+		return "", fmt.Errorf("%w: SVIDs cannot be CA certificates", ErrInvalidSVIDRequest)
+	}
+	return id, nil
+}
+
+// svidApiPassthrough returns the extensions of an SVID with the given
+// SPIFFE ID, which PCA adds to certificates issued with an APIPassthrough
+// template
+func svidApiPassthrough(id string) *acmpcatypes.ApiPassthrough {
+	return &acmpcatypes.ApiPassthrough{
+		Extensions: &acmpcatypes.Extensions{
+			SubjectAlternativeNames: []acmpcatypes.GeneralName{
+				{UniformResourceIdentifier: aws.String(id)},
+			},
+			KeyUsage: &acmpcatypes.KeyUsage{
+				DigitalSignature: true,
+				KeyEncipherment:  true,
+			},
+			ExtendedKeyUsage: []acmpcatypes.ExtendedKeyUsage{
+				{ExtendedKeyUsageType: acmpcatypes.ExtendedKeyUsageTypeServerAuth},
+				{ExtendedKeyUsageType: acmpcatypes.ExtendedKeyUsageTypeClientAuth},
+			},
+		},
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func svidRequest(t *testing.T, username string, csr *x509.CertificateRequest) *cmapi.CertificateRequest {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, csr, key)
+	require.NoError(t, err)
+	return &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "cr1"},
+		Spec: cmapi.CertificateRequestSpec{
+			Request:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+			Username: username,
+		},
+	}
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	return u
+}
+
+func TestSPIFFEID(t *testing.T) {
+	const requester = "system:serviceaccount:team-a:app"
+	id := mustParseURL(t, "spiffe://cluster.local/ns/team-a/sa/app")
+
+	type testCase struct {
+		username      string
+		csr           *x509.CertificateRequest
+		expectedError string
+	}
+
+	tests := map[string]testCase{
+		"valid": {
+			username: requester,
+			csr:      &x509.CertificateRequest{URIs: []*url.URL{id}},
+		},
+		"valid-with-common-name": {
+			username: requester,
+			csr:      &x509.CertificateRequest{Subject: pkix.Name{CommonName: "app"}, URIs: []*url.URL{id}},
+		},
+		"requester-not-service-account": {
+			username:      "alice",
+			csr:           &x509.CertificateRequest{URIs: []*url.URL{id}},
+			expectedError: `invalid SVID request: requester "alice" is not a service account`,
+		},
+		"other-service-account": {
+			username:      "system:serviceaccount:team-b:app",
+			csr:           &x509.CertificateRequest{URIs: []*url.URL{id}},
+			expectedError: "invalid SVID request: requested URI SAN spiffe://cluster.local/ns/team-a/sa/app is not spiffe://cluster.local/ns/team-b/sa/app",
+		},
+		"dns-name": {
+			username:      requester,
+			csr:           &x509.CertificateRequest{DNSNames: []string{"app.example.com"}, URIs: []*url.URL{id}},
+			expectedError: "invalid SVID request: only a URI SAN may be requested",
+		},
+		"no-uri": {
+			username:      requester,
+			csr:           &x509.CertificateRequest{},
+			expectedError: "invalid SVID request: exactly one URI SAN must be requested, got 0",
+		},
+		"two-uris": {
+			username:      requester,
+			csr:           &x509.CertificateRequest{URIs: []*url.URL{id, mustParseURL(t, "spiffe://cluster.local/other")}},
+			expectedError: "invalid SVID request: exactly one URI SAN must be requested, got 2",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := SPIFFEID("cluster.local", svidRequest(t, tc.username, tc.csr))
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.ErrorIs(t, err, ErrInvalidSVIDRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, id.String(), got)
+		})
+	}
+
+	cr := svidRequest(t, requester, &x509.CertificateRequest{URIs: []*url.URL{id}})
+	cr.Spec.IsCA = true
+	_, err := SPIFFEID("cluster.local", cr)
+	assert.ErrorIs(t, err, ErrInvalidSVIDRequest)
+}
+
+func TestPCASignSPIFFE(t *testing.T) {
+	issuances.Clear()
+	t.Cleanup(issuances.Clear)
+
+	client := &workingACMPCAClient{}
+	provisioner := PCAProvisioner{arn: caArn, pcaClient: client, spiffe: &api.SPIFFE{TrustDomain: "cluster.local"}}
+	cr := svidRequest(t, "system:serviceaccount:team-a:app", &x509.CertificateRequest{
+		URIs: []*url.URL{mustParseURL(t, "spiffe://cluster.local/ns/team-a/sa/app")},
+	})
+
+	require.NoError(t, provisioner.Sign(context.TODO(), cr, "", logr.Discard()))
+	input := client.issueCertInput
+	assert.Equal(t, "arn:aws:acm-pca:::template/BlankEndEntityCertificate_APIPassthrough/V1", aws.ToString(input.TemplateArn))
+	require.NotNil(t, input.ApiPassthrough)
+	extensions := input.ApiPassthrough.Extensions
+	require.Len(t, extensions.SubjectAlternativeNames, 1)
+	assert.Equal(t, "spiffe://cluster.local/ns/team-a/sa/app", aws.ToString(extensions.SubjectAlternativeNames[0].UniformResourceIdentifier))
+	assert.True(t, extensions.KeyUsage.DigitalSignature)
+	assert.Equal(t, []acmpcatypes.ExtendedKeyUsage{
+		{ExtendedKeyUsageType: acmpcatypes.ExtendedKeyUsageTypeServerAuth},
+		{ExtendedKeyUsageType: acmpcatypes.ExtendedKeyUsageTypeClientAuth},
+	}, extensions.ExtendedKeyUsage)
+
+	// The default template of the issuer takes precedence
+	issuances.Clear()
+	cr.UID = "other"
+	require.NoError(t, provisioner.Sign(context.TODO(), cr, "CustomSVID/V1", logr.Discard()))
+	assert.Equal(t, "arn:aws:acm-pca:::template/CustomSVID/V1", aws.ToString(client.issueCertInput.TemplateArn))
+
+	invalid := svidRequest(t, "alice", &x509.CertificateRequest{})
+	assert.ErrorIs(t, provisioner.Sign(context.TODO(), invalid, "", logr.Discard()), ErrInvalidSVIDRequest)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	type testCase struct {
+		status         int
+		body           string
+		expectedStatus codes.Code
+	}
+
+	tests := map[string]testCase{
+		"success": {
+			status:         http.StatusOK,
+			body:           `{"Certificate":"cert","CertificateChain":"chain"}`,
+			expectedStatus: codes.Unset,
+		},
+		"failure": {
+			status:         http.StatusBadRequest,
+			body:           `{"__type":"ResourceNotFoundException","message":"not found"}`,
+			expectedStatus: codes.Error,
+		},
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	origProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(origProvider) })
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+				w.Header().Set("X-Amzn-Requestid", "request-id")
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			t.Cleanup(server.Close)
+
+			client := acmpca.New(acmpca.Options{
+				Region:           "us-east-1",
+				BaseEndpoint:     aws.String(server.URL),
+				Credentials:      aws.AnonymousCredentials{},
+				RetryMaxAttempts: 1,
+				APIOptions:       []func(*middleware.Stack) error{addTracingMiddleware},
+			})
+
+			before := len(recorder.Ended())
+			_, _ = client.GetCertificate(context.TODO(), &acmpca.GetCertificateInput{
+				CertificateArn:          aws.String(certArn),
+				CertificateAuthorityArn: aws.String(caArn),
+			})
+
+			spans := recorder.Ended()
+			require.Len(t, spans, before+1)
+			span := spans[before]
+			assert.Equal(t, "ACM PCA.GetCertificate", span.Name())
+			assert.Equal(t, tc.expectedStatus, span.Status().Code)
+			assert.Contains(t, span.Attributes(), attribute.String("rpc.method", "GetCertificate"))
+			assert.Contains(t, span.Attributes(), attribute.String("cloud.region", "us-east-1"))
+			assert.Contains(t, span.Attributes(), attribute.String("aws.request_id", "request-id"))
+		})
+	}
+}
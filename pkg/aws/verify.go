@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/cert-manager/cert-manager/pkg/util/pki"
+)
+
+// validityTolerance is the allowed difference between the requested and the
+// issued end of validity.
+const validityTolerance = 5 * time.Minute
+
+// ErrCertificateMismatch is returned when the certificate returned by PCA does
+// not match the CertificateRequest it was issued for.
+var ErrCertificateMismatch = errors.New("issued certificate does not match the certificate request")
+
+// ErrIdempotencyMismatch is returned, wrapped in ErrCertificateMismatch, when
+// the certificate returned by PCA was issued for another CSR and the
+// idempotency token of the request may have been reused. This happens when
+// PCA treats the request as a retry of an earlier request with the same
+// idempotency token.
+var ErrIdempotencyMismatch = errors.New("certificate was issued for another CSR with the same idempotency token")
+
+// verifyIssuedCertificate checks that the certificate returned by PCA was
+// issued for the CSR of the CertificateRequest, chains up to the returned CA
+// and carries the requested SANs, usages and validity. A certificate issued for
+// another CSR is only attributed to the idempotency token if it may have been
+// reused.
+func verifyIssuedCertificate(cr *cmapi.CertificateRequest, leaf *x509.Certificate, intermediatesPem, rootPem []byte, checkUsages bool) error {
+	csr, err := pki.DecodeX509CertificateRequestBytes(cr.Spec.Request)
+	if err != nil {
+		return err
+	}
+
+	equal, err := pki.PublicKeysEqual(csr.PublicKey, leaf.PublicKey)
+	if err != nil {
+		return err
+	}
+	if !equal {
+		if tokenMayBeReused(cr) {
+			return fmt.Errorf("%w: public key differs from the CSR: %w", ErrCertificateMismatch, ErrIdempotencyMismatch)
+		}
+		return fmt.Errorf("%w: public key differs from the CSR", ErrCertificateMismatch)
+	}
+
+	if err := verifyChain(leaf, intermediatesPem, rootPem); err != nil {
+		return fmt.Errorf("%w: %v", ErrCertificateMismatch, err)
+	}
+
+	if err := verifySANs(csr, leaf); err != nil {
+		return fmt.Errorf("%w: %v", ErrCertificateMismatch, err)
+	}
+
+	if checkUsages && len(cr.Spec.Usages) > 0 {
+		if err := verifyUsages(cr, leaf); err != nil {
+			return fmt.Errorf("%w: %v", ErrCertificateMismatch, err)
+		}
+	}
+
+	if requested, ok := cr.GetAnnotations()[RequestedNotAfterAnnotation]; ok {
+		notAfter, err := time.Parse(time.RFC3339, requested)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s annotation: %v", RequestedNotAfterAnnotation, err)
+		}
+		if diff := leaf.NotAfter.Sub(notAfter); diff > validityTolerance || diff < -validityTolerance {
+			return fmt.Errorf("%w: certificate expires at %s but %s was requested",
+				ErrCertificateMismatch, leaf.NotAfter.UTC().Format(time.RFC3339), requested)
+		}
+	}
+
+	return nil
+}
+
+func verifyChain(leaf *x509.Certificate, intermediatesPem, rootPem []byte) error {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootPem) {
+		return fmt.Errorf("no root CA certificate returned")
+	}
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM(intermediatesPem)
+
+	// Verify at the start of the certificate's validity so that clock skew
+	// with PCA does not fail a freshly issued certificate.
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   leaf.NotBefore,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate does not chain up to the returned CA: %v", err)
+	}
+	return nil
+}
+
+func verifySANs(csr *x509.CertificateRequest, leaf *x509.Certificate) error {
+	for _, name := range csr.DNSNames {
+		if !slices.Contains(leaf.DNSNames, name) {
+			return fmt.Errorf("DNS name %q is missing", name)
+		}
+	}
+	for _, email := range csr.EmailAddresses {
+		if !slices.Contains(leaf.EmailAddresses, email) {
+			return fmt.Errorf("email address %q is missing", email)
+		}
+	}
+	for _, ip := range csr.IPAddresses {
+		if !slices.ContainsFunc(leaf.IPAddresses, func(other net.IP) bool { return ip.Equal(other) }) {
+			return fmt.Errorf("IP address %q is missing", ip)
+		}
+	}
+	for _, uri := range csr.URIs {
+		if !slices.ContainsFunc(leaf.URIs, func(other *url.URL) bool { return uri.String() == other.String() }) {
+			return fmt.Errorf("URI %q is missing", uri)
+		}
+	}
+	return nil
+}
+
+func verifyUsages(cr *cmapi.CertificateRequest, leaf *x509.Certificate) error {
+	keyUsage, extKeyUsages, err := pki.KeyUsagesForCertificateOrCertificateRequest(cr.Spec.Usages, cr.Spec.IsCA)
+	if err != nil {
+		return err
+	}
+	if leaf.KeyUsage&keyUsage != keyUsage {
+		return fmt.Errorf("key usages %b requested but %b issued", keyUsage, leaf.KeyUsage)
+	}
+	if slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageAny) {
+		return nil
+	}
+	for _, usage := range extKeyUsages {
+		if !slices.Contains(leaf.ExtKeyUsage, usage) {
+			return fmt.Errorf("extended key usage %d is missing", usage)
+		}
+	}
+	return nil
+}
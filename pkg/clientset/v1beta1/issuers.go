@@ -20,6 +20,7 @@ var (
 // AWSPCAIssuerInterface is a interface for interacting with a AWSPCAIssuer
 type AWSPCAIssuerInterface interface {
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AWSPCAIssuer, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AWSPCAIssuerList, error)
 	Create(ctx context.Context, issuer *v1beta1.AWSPCAIssuer, opts metav1.CreateOptions) (*v1beta1.AWSPCAIssuer, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
@@ -28,6 +29,7 @@ type AWSPCAIssuerInterface interface {
 // AWSPCAClusterIssuerInterface is a interface for interacting with a AWSPCAClusterIssuer
 type AWSPCAClusterIssuerInterface interface {
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AWSPCAClusterIssuer, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AWSPCAClusterIssuerList, error)
 	Create(ctx context.Context, issuer *v1beta1.AWSPCAClusterIssuer, opts metav1.CreateOptions) (*v1beta1.AWSPCAClusterIssuer, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
@@ -136,3 +138,26 @@ func (c *awspcaClusterIssuerClient) Watch(ctx context.Context, opts metav1.ListO
 		Timeout(timeout).
 		Watch(ctx)
 }
+
+func (c *awspcaIssuerClient) List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AWSPCAIssuerList, error) {
+	result := v1beta1.AWSPCAIssuerList{}
+	err := c.restClient.Get().
+		Namespace(c.ns).
+		Resource(awspcaissuers).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do(ctx).
+		Into(&result)
+
+	return &result, err
+}
+
+func (c *awspcaClusterIssuerClient) List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AWSPCAClusterIssuerList, error) {
+	result := v1beta1.AWSPCAClusterIssuerList{}
+	err := c.restClient.Get().
+		Resource(awspcaclusterissuers).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do(ctx).
+		Into(&result)
+
+	return &result, err
+}
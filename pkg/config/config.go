@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/health"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/yaml"
+)
+
+// New returns a configuration with all defaults applied
+func New() *ControllerConfiguration {
+	c := &ControllerConfiguration{
+		TypeMeta: metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind},
+	}
+	c.Default()
+	return c
+}
+
+// Load reads, defaults and validates the configuration file at path. An empty
+// path returns the default configuration.
+func Load(path string) (*ControllerConfiguration, error) {
+	if path == "" {
+		return New(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	c := new(ControllerConfiguration)
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	c.Default()
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Default sets the default value of every unset field
+func (c *ControllerConfiguration) Default() {
+	if c.Defaults.Region == "" {
+		c.Defaults.Region = os.Getenv("AWS_REGION")
+	}
+	if c.Defaults.Duration == nil {
+		c.Defaults.Duration = &metav1.Duration{Duration: awspca.DEFAULT_DURATION * time.Second}
+	}
+	if c.Controller.MaxConcurrentReconciles == 0 {
+		c.Controller.MaxConcurrentReconciles = 1
+	}
+	if c.Controller.IdempotencyTokenStrategy == "" {
+		c.Controller.IdempotencyTokenStrategy = string(awspca.IdempotencyTokenStrategyRequest)
+	}
+	if c.Controller.SyncPeriod == nil {
+		c.Controller.SyncPeriod = &metav1.Duration{Duration: 10 * time.Hour}
+	}
+	if c.Metrics.BindAddress == "" {
+		c.Metrics.BindAddress = ":8080"
+	}
+	if c.Health.BindAddress == "" {
+		c.Health.BindAddress = ":8081"
+	}
+	if c.Health.IssuerReadinessWindow == nil {
+		c.Health.IssuerReadinessWindow = &metav1.Duration{Duration: health.DefaultIssuerWindow}
+	}
+	if c.LeaderElection.LeaseDuration == nil {
+		c.LeaderElection.LeaseDuration = &metav1.Duration{Duration: 15 * time.Second}
+	}
+	if c.LeaderElection.RenewDeadline == nil {
+		c.LeaderElection.RenewDeadline = &metav1.Duration{Duration: 10 * time.Second}
+	}
+	if c.LeaderElection.RetryPeriod == nil {
+		c.LeaderElection.RetryPeriod = &metav1.Duration{Duration: 2 * time.Second}
+	}
+	if c.LeaderElection.ResourceLock == "" {
+		c.LeaderElection.ResourceLock = resourcelock.LeasesResourceLock
+	}
+	if c.Sharding.Shards == 0 {
+		c.Sharding.Shards = 16
+	}
+	if c.Sharding.LeaseDuration == nil {
+		c.Sharding.LeaseDuration = &metav1.Duration{Duration: 15 * time.Second}
+	}
+	if c.Sharding.RenewPeriod == nil {
+		c.Sharding.RenewPeriod = &metav1.Duration{Duration: 5 * time.Second}
+	}
+	if c.Tracing.SampleRatio == nil {
+		ratio := 1.0
+		c.Tracing.SampleRatio = &ratio
+	}
+}
+
+// Validate returns an error listing every invalid field
+func (c *ControllerConfiguration) Validate() error {
+	var errs field.ErrorList
+
+	if c.APIVersion != APIVersion {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{APIVersion}))
+	}
+	if c.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+
+	defaults := field.NewPath("defaults")
+	if c.Defaults.Duration.Duration <= 0 {
+		errs = append(errs, field.Invalid(defaults.Child("duration"), c.Defaults.Duration.Duration.String(), "must be positive"))
+	}
+
+	controller := field.NewPath("controller")
+	if c.Controller.MaxConcurrentReconciles < 1 {
+		errs = append(errs, field.Invalid(controller.Child("maxConcurrentReconciles"), c.Controller.MaxConcurrentReconciles, "must be at least 1"))
+	}
+	if c.Controller.SyncPeriod.Duration <= 0 {
+		errs = append(errs, field.Invalid(controller.Child("syncPeriod"), c.Controller.SyncPeriod.Duration.String(), "must be positive"))
+	}
+
+	if strategy := awspca.IdempotencyTokenStrategy(c.Controller.IdempotencyTokenStrategy); !slices.Contains(awspca.IdempotencyTokenStrategies, strategy) {
+		supported := make([]string, len(awspca.IdempotencyTokenStrategies))
+		for i, s := range awspca.IdempotencyTokenStrategies {
+			supported[i] = string(s)
+		}
+		errs = append(errs, field.NotSupported(controller.Child("idempotencyTokenStrategy"), c.Controller.IdempotencyTokenStrategy, supported))
+	}
+
+	client := field.NewPath("kubernetesClient")
+	if c.KubernetesClient.QPS < 0 {
+		errs = append(errs, field.Invalid(client.Child("qps"), c.KubernetesClient.QPS, "must not be negative, use disableRateLimiting instead"))
+	}
+	if c.KubernetesClient.Burst < 0 {
+		errs = append(errs, field.Invalid(client.Child("burst"), c.KubernetesClient.Burst, "must not be negative, use disableRateLimiting instead"))
+	}
+
+	if c.Health.IssuerReadinessWindow.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("health", "issuerReadinessWindow"), c.Health.IssuerReadinessWindow.Duration.String(), "must not be negative"))
+	}
+
+	leaderElection := field.NewPath("leaderElection")
+	lease := c.LeaderElection
+	if lease.RetryPeriod.Duration <= 0 {
+		errs = append(errs, field.Invalid(leaderElection.Child("retryPeriod"), lease.RetryPeriod.Duration.String(), "must be positive"))
+	}
+	// The leader elector jitters the retry period by up to 20%
+	if lease.RenewDeadline.Duration <= time.Duration(leaderelection.JitterFactor*float64(lease.RetryPeriod.Duration)) {
+		errs = append(errs, field.Invalid(leaderElection.Child("renewDeadline"), lease.RenewDeadline.Duration.String(), "must be greater than 1.2 times retryPeriod"))
+	}
+	if lease.LeaseDuration.Duration <= lease.RenewDeadline.Duration {
+		errs = append(errs, field.Invalid(leaderElection.Child("leaseDuration"), lease.LeaseDuration.Duration.String(), "must be greater than renewDeadline"))
+	}
+	if lease.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(lease.Namespace) {
+			errs = append(errs, field.Invalid(leaderElection.Child("namespace"), lease.Namespace, msg))
+		}
+	}
+	if lease.ResourceLock != resourcelock.LeasesResourceLock {
+		errs = append(errs, field.NotSupported(leaderElection.Child("resourceLock"), lease.ResourceLock, []string{resourcelock.LeasesResourceLock}))
+	}
+
+	if ratio := *c.Tracing.SampleRatio; ratio < 0 || ratio > 1 {
+		errs = append(errs, field.Invalid(field.NewPath("tracing", "sampleRatio"), ratio, "must be between 0 and 1"))
+	}
+
+	watch := field.NewPath("watch")
+	for i, ns := range c.Watch.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(watch.Child("namespaces").Index(i), ns, msg))
+		}
+	}
+	if _, err := labels.Parse(c.Watch.IssuerLabelSelector); err != nil {
+		errs = append(errs, field.Invalid(watch.Child("issuerLabelSelector"), c.Watch.IssuerLabelSelector, err.Error()))
+	}
+	if c.Watch.InstanceName != "" {
+		for _, msg := range validation.IsDNS1123Label(c.Watch.InstanceName) {
+			errs = append(errs, field.Invalid(watch.Child("instanceName"), c.Watch.InstanceName, msg))
+		}
+	}
+
+	sharding := field.NewPath("sharding")
+	if c.Sharding.Shards < 1 {
+		errs = append(errs, field.Invalid(sharding.Child("shards"), c.Sharding.Shards, "must be at least 1"))
+	}
+	if c.Sharding.RenewPeriod.Duration <= 0 {
+		errs = append(errs, field.Invalid(sharding.Child("renewPeriod"), c.Sharding.RenewPeriod.Duration.String(), "must be positive"))
+	}
+	// Leases are held for whole seconds
+	if c.Sharding.LeaseDuration.Duration%time.Second != 0 {
+		errs = append(errs, field.Invalid(sharding.Child("leaseDuration"), c.Sharding.LeaseDuration.Duration.String(), "must be a whole number of seconds"))
+	}
+	if c.Sharding.LeaseDuration.Duration <= c.Sharding.RenewPeriod.Duration {
+		errs = append(errs, field.Invalid(sharding.Child("leaseDuration"), c.Sharding.LeaseDuration.Duration.String(), "must be greater than renewPeriod"))
+	}
+	if c.Sharding.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(c.Sharding.Namespace) {
+			errs = append(errs, field.Invalid(sharding.Child("namespace"), c.Sharding.Namespace, msg))
+		}
+	}
+
+	return errs.ToAggregate()
+}
+
+// AWSDefaults returns the defaults applied to issuers and CertificateRequests
+func (c *ControllerConfiguration) AWSDefaults() awspca.Defaults {
+	return awspca.Defaults{
+		Region:       c.Defaults.Region,
+		Duration:     c.Defaults.Duration.Duration,
+		TemplateName: c.Defaults.TemplateName,
+	}
+}
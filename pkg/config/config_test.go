@@ -0,0 +1,369 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoad(t *testing.T) {
+	type testCase struct {
+		content       string
+		expectFailure bool
+		check         func(*testing.T, *ControllerConfiguration)
+	}
+
+	tests := map[string]testCase{
+		"success-defaults": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+`,
+			check: func(t *testing.T, c *ControllerConfiguration) {
+				assert.Equal(t, New(), c)
+				assert.Equal(t, 720*time.Hour, c.Defaults.Duration.Duration)
+				assert.Equal(t, ":8080", c.Metrics.BindAddress)
+				assert.Equal(t, ":8081", c.Health.BindAddress)
+				assert.Equal(t, 10*time.Minute, c.Health.IssuerReadinessWindow.Duration)
+				assert.Equal(t, 1, c.Controller.MaxConcurrentReconciles)
+				assert.Equal(t, "Request", c.Controller.IdempotencyTokenStrategy)
+				assert.Equal(t, 15*time.Second, c.LeaderElection.LeaseDuration.Duration)
+				assert.Equal(t, 10*time.Second, c.LeaderElection.RenewDeadline.Duration)
+				assert.Equal(t, 2*time.Second, c.LeaderElection.RetryPeriod.Duration)
+				assert.Equal(t, "leases", c.LeaderElection.ResourceLock)
+				assert.False(t, c.Sharding.Enabled)
+				assert.Equal(t, 16, c.Sharding.Shards)
+				assert.Equal(t, 15*time.Second, c.Sharding.LeaseDuration.Duration)
+				assert.Equal(t, 5*time.Second, c.Sharding.RenewPeriod.Duration)
+			},
+		},
+		"success-all-fields": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  region: eu-west-1
+  duration: 24h
+  templateName: EndEntityCertificate/V1
+controller:
+  maxConcurrentReconciles: 4
+  syncPeriod: 1h
+  disableApprovedCheck: true
+  idempotencyTokenStrategy: Name
+kubernetesClient:
+  qps: 50
+  burst: 100
+metrics:
+  bindAddress: ":9090"
+health:
+  checkDefaultIdentity: true
+  issuerReadinessWindow: 0s
+leaderElection:
+  enabled: true
+  leaseDuration: 60s
+  renewDeadline: 40s
+  retryPeriod: 5s
+  namespace: cert-manager
+  resourceLock: leases
+tracing:
+  endpoint: collector:4318
+  sampleRatio: 0.5
+watch:
+  namespaces: [team-a, team-b]
+  issuerLabelSelector: team in (a,b)
+  instanceName: team-ab
+sharding:
+  enabled: true
+  shards: 32
+  leaseDuration: 30s
+  renewPeriod: 10s
+  namespace: cert-manager
+`,
+			check: func(t *testing.T, c *ControllerConfiguration) {
+				defaults := c.AWSDefaults()
+				assert.Equal(t, "eu-west-1", defaults.Region)
+				assert.Equal(t, 24*time.Hour, defaults.Duration)
+				assert.Equal(t, "EndEntityCertificate/V1", defaults.TemplateName)
+				assert.Equal(t, 4, c.Controller.MaxConcurrentReconciles)
+				assert.Equal(t, time.Hour, c.Controller.SyncPeriod.Duration)
+				assert.True(t, c.Controller.DisableApprovedCheck)
+				assert.Equal(t, "Name", c.Controller.IdempotencyTokenStrategy)
+				assert.Equal(t, float32(50), c.KubernetesClient.QPS)
+				assert.Equal(t, 100, c.KubernetesClient.Burst)
+				assert.Equal(t, ":9090", c.Metrics.BindAddress)
+				assert.True(t, c.Health.CheckDefaultIdentity)
+				assert.Equal(t, time.Duration(0), c.Health.IssuerReadinessWindow.Duration)
+				assert.True(t, c.LeaderElection.Enabled)
+				assert.Equal(t, time.Minute, c.LeaderElection.LeaseDuration.Duration)
+				assert.Equal(t, 40*time.Second, c.LeaderElection.RenewDeadline.Duration)
+				assert.Equal(t, 5*time.Second, c.LeaderElection.RetryPeriod.Duration)
+				assert.Equal(t, "cert-manager", c.LeaderElection.Namespace)
+				assert.Equal(t, "leases", c.LeaderElection.ResourceLock)
+				assert.Equal(t, "collector:4318", c.Tracing.Endpoint)
+				assert.Equal(t, 0.5, *c.Tracing.SampleRatio)
+				assert.Equal(t, []string{"team-a", "team-b"}, c.Watch.Namespaces)
+				assert.Equal(t, "team in (a,b)", c.Watch.IssuerLabelSelector)
+				assert.Equal(t, "team-ab", c.Watch.InstanceName)
+				assert.True(t, c.Sharding.Enabled)
+				assert.Equal(t, 32, c.Sharding.Shards)
+				assert.Equal(t, 30*time.Second, c.Sharding.LeaseDuration.Duration)
+				assert.Equal(t, 10*time.Second, c.Sharding.RenewPeriod.Duration)
+				assert.Equal(t, "cert-manager", c.Sharding.Namespace)
+			},
+		},
+		"failure-unknown-field": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  regoin: eu-west-1
+`,
+			expectFailure: true,
+		},
+		"failure-wrong-version": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1
+kind: ControllerConfiguration
+`,
+			expectFailure: true,
+		},
+		"failure-invalid-watch": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+watch:
+  namespaces: [Team_A]
+  issuerLabelSelector: "team in ("
+  instanceName: team.a
+`,
+			expectFailure: true,
+		},
+		"failure-invalid-leader-election": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+leaderElection:
+  leaseDuration: 10s
+  renewDeadline: 10s
+  retryPeriod: 2s
+`,
+			expectFailure: true,
+		},
+		"failure-invalid-leader-election-lock": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+leaderElection:
+  resourceLock: configmaps
+`,
+			expectFailure: true,
+		},
+		"failure-invalid-sharding": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+sharding:
+  shards: -1
+  leaseDuration: 4500ms
+  renewPeriod: 5s
+`,
+			expectFailure: true,
+		},
+		"failure-invalid-values": {
+			content: `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  duration: -1h
+controller:
+  maxConcurrentReconciles: -1
+  idempotencyTokenStrategy: UID
+kubernetesClient:
+  qps: -1
+health:
+  issuerReadinessWindow: -1m
+tracing:
+  sampleRatio: 2
+`,
+			expectFailure: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfig(t, path, tc.content)
+
+			c, err := Load(path)
+			if tc.expectFailure {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, c)
+		})
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	c, err := Load("")
+	require.NoError(t, err)
+	assert.Equal(t, New(), c)
+	assert.NoError(t, c.Validate())
+}
+
+func TestDefaultRegionFromEnvironment(t *testing.T) {
+	t.Setenv("AWS_REGION", "ap-southeast-2")
+	assert.Equal(t, "ap-southeast-2", New().AWSDefaults().Region)
+
+	c := &ControllerConfiguration{Defaults: DefaultsConfiguration{Region: "eu-west-1"}}
+	c.Default()
+	assert.Equal(t, "eu-west-1", c.AWSDefaults().Region)
+}
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  region: eu-west-1
+`)
+	current, err := Load(path)
+	require.NoError(t, err)
+
+	var (
+		mu      sync.Mutex
+		applied []*ControllerConfiguration
+	)
+	watcher := &Watcher{
+		Path:    path,
+		Log:     logrtesting.NewTestLogger(t),
+		Current: current,
+		Apply: func(c *ControllerConfiguration) {
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, c)
+		},
+	}
+	assert.False(t, watcher.NeedLeaderElection())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- watcher.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	lastApplied := func() *ControllerConfiguration {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(applied) == 0 {
+			return nil
+		}
+		return applied[len(applied)-1]
+	}
+
+	// An invalid file is ignored
+	writeConfig(t, path, `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  duration: -1h
+`)
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, lastApplied())
+
+	writeConfig(t, path, `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  region: us-west-2
+`)
+	assert.Eventually(t, func() bool {
+		c := lastApplied()
+		return c != nil && c.Defaults.Region == "us-west-2"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcherReloadWithOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  region: eu-west-1
+`)
+	override := func(c *ControllerConfiguration) {
+		c.Controller.MaxConcurrentReconciles = 4
+	}
+	current, err := Load(path)
+	require.NoError(t, err)
+	override(current)
+
+	var messages []string
+	var applied *ControllerConfiguration
+	watcher := &Watcher{
+		Path: path,
+		Log: funcr.New(func(prefix, args string) {
+			messages = append(messages, args)
+		}, funcr.Options{}),
+		Current:  current,
+		Override: override,
+		Apply:    func(c *ControllerConfiguration) { applied = c },
+		last:     current,
+	}
+
+	writeConfig(t, path, `
+apiVersion: config.awspca.cert-manager.io/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  region: us-west-2
+`)
+	watcher.reload()
+	require.NotNil(t, applied)
+	assert.Equal(t, "us-west-2", applied.Defaults.Region)
+	assert.Equal(t, 4, applied.Controller.MaxConcurrentReconciles)
+	for _, message := range messages {
+		assert.NotContains(t, message, "restart")
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	a, b := New(), New()
+	b.Defaults.Region = "eu-west-1"
+	assert.False(t, restartRequired(a, b))
+
+	b.Controller.MaxConcurrentReconciles = 2
+	assert.True(t, restartRequired(a, b))
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIVersion is the version of the configuration file format
+	APIVersion = "config.awspca.cert-manager.io/v1alpha1"
+	// Kind is the kind of the configuration file
+	Kind = "ControllerConfiguration"
+)
+
+// ControllerConfiguration configures the controller manager
+type ControllerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Defaults for issuers and CertificateRequests that do not specify a value.
+	// Changes are applied without a restart.
+	Defaults DefaultsConfiguration `json:"defaults,omitempty"`
+	// Controller configures the reconcilers
+	Controller ControllerOptions `json:"controller,omitempty"`
+	// KubernetesClient configures the client used to talk to the API server
+	KubernetesClient KubernetesClientConfiguration `json:"kubernetesClient,omitempty"`
+	// Metrics configures the metrics endpoint
+	Metrics MetricsConfiguration `json:"metrics,omitempty"`
+	// Health configures the health probe endpoint
+	Health HealthConfiguration `json:"health,omitempty"`
+	// LeaderElection configures leader election
+	LeaderElection LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+	// Tracing configures the export of traces
+	Tracing TracingConfiguration `json:"tracing,omitempty"`
+	// Watch limits the objects handled by this controller instance
+	Watch WatchConfiguration `json:"watch,omitempty"`
+	// Audit configures the audit log of CertificateRequest decisions
+	Audit AuditConfiguration `json:"audit,omitempty"`
+	// Sharding spreads CertificateRequests over the replicas of the
+	// controller
+	Sharding ShardingConfiguration `json:"sharding,omitempty"`
+}
+
+// DefaultsConfiguration holds defaults for issuers and CertificateRequests
+type DefaultsConfiguration struct {
+	// Region used by issuers without a region
+	Region string `json:"region,omitempty"`
+	// Duration of certificates whose CertificateRequest has no duration.
+	// Defaults to 720h.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// TemplateName used by issuers without a default template name
+	TemplateName string `json:"templateName,omitempty"`
+}
+
+// ControllerOptions configures the reconcilers
+type ControllerOptions struct {
+	// MaxConcurrentReconciles is the number of objects each controller
+	// reconciles in parallel. Defaults to 1.
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+	// SyncPeriod is the minimum frequency at which watched objects are
+	// reconciled. Defaults to 10h.
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+	// DisableApprovedCheck disables waiting for CertificateRequests to be
+	// approved before signing.
+	DisableApprovedCheck bool `json:"disableApprovedCheck,omitempty"`
+	// IdempotencyTokenStrategy selects what the idempotency token passed to
+	// PCA is derived from: Request (namespace, name, UID and CSR) or Name
+	// (namespace and name only, as earlier releases did). Defaults to Request.
+	IdempotencyTokenStrategy string `json:"idempotencyTokenStrategy,omitempty"`
+	// RequireCARevocation keeps issuers whose CA has neither a CRL nor OCSP
+	// enabled from becoming Ready, regardless of their revocationCheck.
+	RequireCARevocation bool `json:"requireCARevocation,omitempty"`
+	// EnableApprover runs the built-in approver, which approves or denies
+	// the CertificateRequests of issuers with an approval policy.
+	EnableApprover bool `json:"enableApprover,omitempty"`
+}
+
+// KubernetesClientConfiguration configures the Kubernetes client
+type KubernetesClientConfiguration struct {
+	// QPS is the sustained rate of requests to the API server. Defaults to
+	// the client-go default.
+	QPS float32 `json:"qps,omitempty"`
+	// Burst is the maximum burst of requests to the API server. Defaults to
+	// the client-go default.
+	Burst int `json:"burst,omitempty"`
+	// DisableRateLimiting disables client-side rate limiting (only use if
+	// API Priority & Fairness is enabled on the cluster).
+	DisableRateLimiting bool `json:"disableRateLimiting,omitempty"`
+}
+
+// MetricsConfiguration configures the metrics endpoint
+type MetricsConfiguration struct {
+	// BindAddress is the address the metrics endpoint binds to.
+	// Defaults to :8080.
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// HealthConfiguration configures the health probe endpoint
+type HealthConfiguration struct {
+	// BindAddress is the address the probe endpoint binds to.
+	// Defaults to :8081.
+	BindAddress string `json:"bindAddress,omitempty"`
+	// CheckDefaultIdentity reports the controller unready while it cannot
+	// call sts.GetCallerIdentity with its default identity, for example an
+	// IRSA role.
+	CheckDefaultIdentity bool `json:"checkDefaultIdentity,omitempty"`
+	// IssuerReadinessWindow is how long all issuers may fail verification
+	// before the controller reports unready. Defaults to 10m, 0 disables the
+	// check.
+	IssuerReadinessWindow *metav1.Duration `json:"issuerReadinessWindow,omitempty"`
+}
+
+// LeaderElectionConfiguration configures leader election
+type LeaderElectionConfiguration struct {
+	// Enabled ensures there is only one active controller manager
+	Enabled bool `json:"enabled,omitempty"`
+	// LeaseDuration is how long non-leader candidates wait before acquiring
+	// leadership of a lease that was not renewed. Defaults to 15s.
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+	// RenewDeadline is how long the leader retries renewing its lease before
+	// giving up leadership. Defaults to 10s.
+	RenewDeadline *metav1.Duration `json:"renewDeadline,omitempty"`
+	// RetryPeriod is how long candidates wait between attempts to acquire or
+	// renew the lease. Defaults to 2s.
+	RetryPeriod *metav1.Duration `json:"retryPeriod,omitempty"`
+	// Namespace the lease is created in. Defaults to the namespace of the
+	// controller.
+	Namespace string `json:"namespace,omitempty"`
+	// ResourceLock is the kind of resource the lease is held on. Defaults to
+	// leases, the only kind supported.
+	ResourceLock string `json:"resourceLock,omitempty"`
+}
+
+// TracingConfiguration configures the export of traces
+type TracingConfiguration struct {
+	// Endpoint is the host and port of an OTLP/HTTP collector. Tracing is
+	// disabled if empty.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Insecure disables TLS when exporting traces to the collector
+	Insecure bool `json:"insecure,omitempty"`
+	// SampleRatio is the fraction of traces that are sampled. Defaults to 1.
+	SampleRatio *float64 `json:"sampleRatio,omitempty"`
+}
+
+// WatchConfiguration limits the objects handled by a controller instance, so
+// that several instances can run in one cluster
+type WatchConfiguration struct {
+	// Namespaces the controller watches. All namespaces are watched if empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// IssuerLabelSelector selects the issuers handled by this instance.
+	// All issuers are handled if empty.
+	IssuerLabelSelector string `json:"issuerLabelSelector,omitempty"`
+	// InstanceName restricts this instance to issuers with a matching
+	// aws-privateca-issuer/instance annotation. If empty, only issuers
+	// without the annotation are handled.
+	InstanceName string `json:"instanceName,omitempty"`
+}
+
+// AuditConfiguration configures the audit log of CertificateRequest decisions
+type AuditConfiguration struct {
+	// Path of the file audit records are appended to as JSON lines, or "-"
+	// for stdout. The audit log is disabled if empty.
+	Path string `json:"path,omitempty"`
+}
+
+// ShardingConfiguration spreads CertificateRequests over the replicas of the
+// controller, which each sign those of the shards they hold a lease for
+type ShardingConfiguration struct {
+	// Enabled runs the CertificateRequest controller on every replica, not
+	// only on the leader
+	Enabled bool `json:"enabled,omitempty"`
+	// Shards is the number of shards CertificateRequests are spread over. It
+	// must be the same on every replica, and limits how many replicas share
+	// the work. Defaults to 16.
+	Shards int `json:"shards,omitempty"`
+	// LeaseDuration is how long the shards of a replica that stopped renewing
+	// its leases wait before they are taken over. Defaults to 15s.
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+	// RenewPeriod is how often replicas renew their leases and reassign the
+	// shards. Defaults to 5s.
+	RenewPeriod *metav1.Duration `json:"renewPeriod,omitempty"`
+	// Namespace the leases are created in. Defaults to the namespace of the
+	// leader election lease.
+	Namespace string `json:"namespace,omitempty"`
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+)
+
+// Watcher reloads the configuration file when it changes and applies the
+// settings that can be changed without a restart.
+type Watcher struct {
+	Path string
+	Log  logr.Logger
+	// Current is the configuration the manager was started with
+	Current *ControllerConfiguration
+	// Override applies the command line flags that take precedence over the
+	// file to every reloaded configuration, as it was applied to Current
+	Override func(*ControllerConfiguration)
+	// Apply is called with every valid configuration that differs from the
+	// previously loaded one
+	Apply func(*ControllerConfiguration)
+
+	last *ControllerConfiguration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, as every
+// replica has to pick up configuration changes.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start watches the configuration file until ctx is done
+func (w *Watcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Watch the directory rather than the file, as ConfigMap volumes are
+	// updated by swapping a symlink.
+	if err := watcher.Add(filepath.Dir(w.Path)); err != nil {
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+	w.last = w.Current
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			w.reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.Log.Error(err, "error watching config file")
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	c, err := Load(w.Path)
+	if err != nil {
+		w.Log.Error(err, "ignoring changed config file")
+		return
+	}
+	if w.Override != nil {
+		w.Override(c)
+	}
+	if reflect.DeepEqual(c, w.last) {
+		return
+	}
+
+	if restartRequired(w.Current, c) {
+		w.Log.Info("config file changed settings that only take effect after a restart")
+	}
+	w.Log.Info("reloaded config file", "path", w.Path)
+	w.Apply(c)
+	w.last = c
+}
+
+// restartRequired returns whether a and b differ in settings that cannot be
+// changed without a restart
+func restartRequired(a, b *ControllerConfiguration) bool {
+	x, y := *a, *b
+	x.Defaults, y.Defaults = DefaultsConfiguration{}, DefaultsConfiguration{}
+	return !reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+var (
+	errInvalidCSR            = errors.New("failed to decode CSR")
+	errInvalidApprovalPolicy = errors.New("invalid approval policy")
+)
+
+// validateApprovalPolicy returns an error wrapping errInvalidApprovalPolicy
+// if the policy cannot be evaluated
+func validateApprovalPolicy(policy *api.ApprovalPolicy) error {
+	for _, r := range policy.AllowedIPRanges {
+		if _, _, err := net.ParseCIDR(r); err != nil {
+			return fmt.Errorf("%w: invalid allowed IP range %s: %v", errInvalidApprovalPolicy, r, err)
+		}
+	}
+	return nil
+}
+
+// evaluateApprovalPolicy returns the reasons a CertificateRequest violates
+// an approval policy, or none if it may be approved. The policy is validated
+// first, so that an invalid policy is reported whatever the CSR contains.
+func evaluateApprovalPolicy(policy *api.ApprovalPolicy, cr *cmapi.CertificateRequest) ([]string, error) {
+	if err := validateApprovalPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(cr.Spec.Request)
+	if block == nil {
+		return nil, errInvalidCSR
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCSR, err)
+	}
+
+	var violations []string
+	if cn := csr.Subject.CommonName; cn != "" &&
+		!matchesAnyPattern(policy.AllowedCommonNames, cn, "") &&
+		!matchesAnyPattern(policy.AllowedDNSNames, cn, ".") {
+		violations = append(violations, fmt.Sprintf("common name %s is not allowed", cn))
+	}
+	for _, name := range csr.DNSNames {
+		if !matchesAnyPattern(policy.AllowedDNSNames, name, ".") {
+			violations = append(violations, fmt.Sprintf("DNS name %s is not allowed", name))
+		}
+	}
+	for _, ip := range csr.IPAddresses {
+		if !inAnyRange(policy.AllowedIPRanges, ip) {
+			violations = append(violations, fmt.Sprintf("IP address %s is not allowed", ip))
+		}
+	}
+	for _, uri := range csr.URIs {
+		if !matchesAnyPattern(policy.AllowedURIs, uri.String(), "") {
+			violations = append(violations, fmt.Sprintf("URI %s is not allowed", uri))
+		}
+	}
+	for _, email := range csr.EmailAddresses {
+		if !matchesAnyPattern(policy.AllowedEmailAddresses, email, "") {
+			violations = append(violations, fmt.Sprintf("email address %s is not allowed", email))
+		}
+	}
+
+	if len(policy.AllowedUsages) > 0 {
+		for _, usage := range cr.Spec.Usages {
+			if !slices.Contains(policy.AllowedUsages, string(usage)) {
+				violations = append(violations, fmt.Sprintf("usage %s is not allowed", usage))
+			}
+		}
+	}
+
+	if policy.MaxDuration != nil {
+		duration := awspca.GetDefaults().Duration
+		if cr.Spec.Duration != nil {
+			duration = cr.Spec.Duration.Duration
+		}
+		if duration > policy.MaxDuration.Duration {
+			violations = append(violations, fmt.Sprintf("duration %s exceeds the maximum of %s", duration, policy.MaxDuration.Duration))
+		}
+	}
+
+	if cr.Spec.IsCA && !policy.AllowCA {
+		violations = append(violations, "CA certificates are not allowed")
+	}
+	return violations, nil
+}
+
+// matchesAnyPattern returns whether value matches one of the patterns, in
+// which * matches any characters except the separator, if any
+func matchesAnyPattern(patterns []string, value, separator string) bool {
+	wildcard := ".*"
+	if separator != "" {
+		wildcard = "[^" + regexp.QuoteMeta(separator) + "]*"
+	}
+	for _, pattern := range patterns {
+		expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, wildcard) + "$"
+		if regexp.MustCompile(expr).MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// inAnyRange returns whether ip is in one of the CIDR ranges, which have
+// been checked by validateApprovalPolicy
+func inAnyRange(ranges []string, ip net.IP) bool {
+	for _, r := range ranges {
+		if _, ipNet, err := net.ParseCIDR(r); err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+// generateCSR returns a PEM encoded CSR for the given template
+func generateCSR(t *testing.T, template *x509.CertificateRequest) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestEvaluateApprovalPolicy(t *testing.T) {
+	policy := &issuerapi.ApprovalPolicy{
+		AllowedCommonNames:    []string{"app"},
+		AllowedDNSNames:       []string{"*.example.com"},
+		AllowedIPRanges:       []string{"10.0.0.0/8"},
+		AllowedURIs:           []string{"spiffe://cluster.local/ns/*"},
+		AllowedEmailAddresses: []string{"*@example.com"},
+		AllowedUsages:         []string{string(cmapi.UsageServerAuth), string(cmapi.UsageClientAuth)},
+		MaxDuration:           &metav1.Duration{Duration: 90 * 24 * time.Hour},
+	}
+	spiffe, err := url.Parse("spiffe://cluster.local/ns/team-a/sa/app")
+	require.NoError(t, err)
+
+	type testCase struct {
+		policy             *issuerapi.ApprovalPolicy
+		csr                *x509.CertificateRequest
+		spec               cmapi.CertificateRequestSpec
+		expectedViolations []string
+		expectedError      bool
+	}
+
+	tests := map[string]testCase{
+		"allowed": {
+			policy: policy,
+			csr: &x509.CertificateRequest{
+				Subject:        pkix.Name{CommonName: "app"},
+				DNSNames:       []string{"www.example.com"},
+				IPAddresses:    []net.IP{net.ParseIP("10.1.2.3")},
+				URIs:           []*url.URL{spiffe},
+				EmailAddresses: []string{"admin@example.com"},
+			},
+			spec: cmapi.CertificateRequestSpec{
+				Usages:   []cmapi.KeyUsage{cmapi.UsageServerAuth},
+				Duration: &metav1.Duration{Duration: 24 * time.Hour},
+			},
+		},
+		"common-name-matching-dns-name": {
+			policy: policy,
+			csr:    &x509.CertificateRequest{Subject: pkix.Name{CommonName: "www.example.com"}},
+		},
+		"wildcard-matches-single-label": {
+			policy:             policy,
+			csr:                &x509.CertificateRequest{DNSNames: []string{"a.b.example.com", "example.com"}},
+			expectedViolations: []string{"DNS name a.b.example.com is not allowed", "DNS name example.com is not allowed"},
+		},
+		"names-denied": {
+			policy: policy,
+			csr: &x509.CertificateRequest{
+				Subject:        pkix.Name{CommonName: "other"},
+				IPAddresses:    []net.IP{net.ParseIP("192.168.0.1")},
+				EmailAddresses: []string{"admin@other.com"},
+			},
+			expectedViolations: []string{
+				"common name other is not allowed",
+				"IP address 192.168.0.1 is not allowed",
+				"email address admin@other.com is not allowed",
+			},
+		},
+		"names-without-patterns-denied": {
+			policy:             &issuerapi.ApprovalPolicy{},
+			csr:                &x509.CertificateRequest{DNSNames: []string{"www.example.com"}},
+			expectedViolations: []string{"DNS name www.example.com is not allowed"},
+		},
+		"usage-denied": {
+			policy:             policy,
+			csr:                &x509.CertificateRequest{},
+			spec:               cmapi.CertificateRequestSpec{Usages: []cmapi.KeyUsage{cmapi.UsageCodeSigning}},
+			expectedViolations: []string{"usage code signing is not allowed"},
+		},
+		"duration-denied": {
+			policy:             policy,
+			csr:                &x509.CertificateRequest{},
+			spec:               cmapi.CertificateRequestSpec{Duration: &metav1.Duration{Duration: 365 * 24 * time.Hour}},
+			expectedViolations: []string{"duration 8760h0m0s exceeds the maximum of 2160h0m0s"},
+		},
+		"default-duration-checked": {
+			policy:             &issuerapi.ApprovalPolicy{MaxDuration: &metav1.Duration{Duration: 24 * time.Hour}},
+			csr:                &x509.CertificateRequest{},
+			expectedViolations: []string{"duration 720h0m0s exceeds the maximum of 24h0m0s"},
+		},
+		"ca-denied": {
+			policy:             policy,
+			csr:                &x509.CertificateRequest{},
+			spec:               cmapi.CertificateRequestSpec{IsCA: true},
+			expectedViolations: []string{"CA certificates are not allowed"},
+		},
+		"ca-allowed": {
+			policy: &issuerapi.ApprovalPolicy{AllowCA: true},
+			csr:    &x509.CertificateRequest{},
+			spec:   cmapi.CertificateRequestSpec{IsCA: true},
+		},
+		"invalid-ip-range": {
+			policy:        &issuerapi.ApprovalPolicy{AllowedIPRanges: []string{"10.0.0.0"}},
+			csr:           &x509.CertificateRequest{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}},
+			expectedError: true,
+		},
+		"invalid-ip-range-without-ip-addresses": {
+			policy:        &issuerapi.ApprovalPolicy{AllowedDNSNames: []string{"*.example.com"}, AllowedIPRanges: []string{"10.0.0.0/8", "10.0.0.0"}},
+			csr:           &x509.CertificateRequest{DNSNames: []string{"www.example.com"}},
+			expectedError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.spec.Request = generateCSR(t, tc.csr)
+			violations, err := evaluateApprovalPolicy(tc.policy, &cmapi.CertificateRequest{Spec: tc.spec})
+			if tc.expectedError {
+				assert.ErrorIs(t, err, errInvalidApprovalPolicy)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedViolations, violations)
+		})
+	}
+
+	_, err = evaluateApprovalPolicy(policy, &cmapi.CertificateRequest{Spec: cmapi.CertificateRequestSpec{Request: []byte("invalid")}})
+	assert.ErrorIs(t, err, errInvalidCSR)
+}
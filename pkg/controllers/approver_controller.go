@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+)
+
+// ApprovalReason is the reason of the Approved and Denied conditions set by
+// the built-in approver
+const ApprovalReason = "awspca.cert-manager.io"
+
+// CertificateRequestApprover approves or denies the CertificateRequests of
+// issuers with an approval policy
+type CertificateRequestApprover struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// IssuerFilter selects the issuers whose CertificateRequests are
+	// approved by this instance
+	IssuerFilter IssuerFilter
+}
+
+// +kubebuilder:rbac:groups=cert-manager.io,resources=signers,verbs=approve,resourceNames=awspcaissuers.awspca.cert-manager.io/*;awspcaclusterissuers.awspca.cert-manager.io/*
+
+// Reconcile approves or denies a CertificateRequest that is neither, if its
+// issuer has an approval policy
+func (r *CertificateRequestApprover) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("certificaterequest", req.NamespacedName)
+	cr := new(cmapi.CertificateRequest)
+	if err := r.Client.Get(ctx, req.NamespacedName, cr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if cr.Spec.IssuerRef.Group != api.GroupVersion.Group {
+		return ctrl.Result{}, nil
+	}
+	if cmutil.CertificateRequestIsApproved(cr) || cmutil.CertificateRequestIsDenied(cr) {
+		return ctrl.Result{}, nil
+	}
+
+	issuerName := types.NamespacedName{
+		Namespace: cr.Namespace,
+		Name:      cr.Spec.IssuerRef.Name,
+	}
+	iss, err := util.GetIssuer(ctx, r.Client, cr.Spec.IssuerRef.Kind, issuerName)
+	if errors.Is(err, util.ErrUnknownIssuerKind) {
+		// Left for the CertificateRequest controller to fail
+		return ctrl.Result{}, nil
+	}
+	if apierrors.IsNotFound(err) {
+		// Without an issuer there is no policy. Creating the issuer requeues
+		// the CertificateRequest.
+		log.V(4).Info("issuer not found", "issuer", issuerName)
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		log.Error(err, "failed to retrieve Issuer resource")
+		return ctrl.Result{}, err
+	}
+	policy := iss.GetSpec().ApprovalPolicy
+	if policy == nil || !r.IssuerFilter.Matches(iss) {
+		return ctrl.Result{}, nil
+	}
+
+	violations, err := evaluateApprovalPolicy(policy, cr)
+	if errors.Is(err, errInvalidApprovalPolicy) {
+		// Left pending until the policy is fixed, which requeues the
+		// CertificateRequests of the issuer
+		log.Error(err, "failed to evaluate approval policy", "issuer", issuerName)
+		r.Recorder.Event(iss, core.EventTypeWarning, "InvalidApprovalPolicy", err.Error())
+		return ctrl.Result{}, err
+	}
+	if err != nil {
+		violations = []string{err.Error()}
+	}
+
+	if len(violations) == 0 {
+		message := "Approved by the approval policy of " + iss.GetName()
+		cmutil.SetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionApproved, cmmeta.ConditionTrue, ApprovalReason, message)
+		r.Recorder.Event(cr, core.EventTypeNormal, "Approved", message)
+	} else {
+		message := "Denied by the approval policy of " + iss.GetName() + ": " + strings.Join(violations, "; ")
+		cmutil.SetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionDenied, cmmeta.ConditionTrue, ApprovalReason, message)
+		r.Recorder.Event(cr, core.EventTypeWarning, "Denied", message)
+	}
+
+	if err := r.Client.Status().Update(ctx, cr); err != nil {
+		if apierrors.IsConflict(err) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// requestsForIssuer returns the CertificateRequests of an issuer that are
+// neither approved nor denied, so that they are approved as soon as an
+// approval policy is added or changed
+func (r *CertificateRequestApprover) requestsForIssuer(ctx context.Context, obj client.Object) []reconcile.Request {
+	iss, ok := obj.(api.GenericIssuer)
+	if !ok {
+		return nil
+	}
+
+	crList := new(cmapi.CertificateRequestList)
+	if err := r.Client.List(ctx, crList, client.MatchingFields{
+		issuerRefIndex: issuerRefKey(issuerKind(iss), iss.GetNamespace(), iss.GetName()),
+	}); err != nil {
+		r.Log.Error(err, "failed to list the CertificateRequests of issuer", "issuer", client.ObjectKeyFromObject(iss))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range crList.Items {
+		cr := &crList.Items[i]
+		if cmutil.CertificateRequestIsApproved(cr) || cmutil.CertificateRequestIsDenied(cr) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cr)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. It relies on the
+// issuerRefIndex registered by the CertificateRequestReconciler.
+func (r *CertificateRequestApprover) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("certificaterequest-approver").
+		For(&cmapi.CertificateRequest{}).
+		Watches(&api.AWSPCAIssuer{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIssuer), builder.WithPredicates(predicate.GenerationChangedPredicate{}, r.IssuerFilter.Predicate())).
+		Watches(&api.AWSPCAClusterIssuer{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIssuer), builder.WithPredicates(predicate.GenerationChangedPredicate{}, r.IssuerFilter.Predicate())).
+		Complete(r)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"testing"
+
+	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+func TestCertificateRequestApprover(t *testing.T) {
+	issuer := func(policy *issuerapi.ApprovalPolicy) *issuerapi.AWSPCAIssuer {
+		return &issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+			Spec: issuerapi.AWSPCAIssuerSpec{
+				Arn:            "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				ApprovalPolicy: policy,
+			},
+		}
+	}
+	policy := &issuerapi.ApprovalPolicy{AllowedDNSNames: []string{"*.example.com"}}
+	certificateRequest := func(dnsName string, mods ...cmgen.CertificateRequestModifier) *cmapi.CertificateRequest {
+		mods = append([]cmgen.CertificateRequestModifier{
+			cmgen.SetCertificateRequestNamespace("ns1"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "issuer1",
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  "AWSPCAIssuer",
+			}),
+			cmgen.SetCertificateRequestCSR(generateCSR(t, &x509.CertificateRequest{DNSNames: []string{dnsName}})),
+		}, mods...)
+		return cmgen.CertificateRequest("cr1", mods...)
+	}
+
+	type testCase struct {
+		objects          []client.Object
+		expectedApproved bool
+		expectedDenied   bool
+		expectedMessage  string
+		expectedError    bool
+		expectedEvent    string
+	}
+
+	tests := map[string]testCase{
+		"approved": {
+			objects:          []client.Object{issuer(policy), certificateRequest("www.example.com")},
+			expectedApproved: true,
+			expectedMessage:  "Approved by the approval policy of issuer1",
+		},
+		"denied": {
+			objects:         []client.Object{issuer(policy), certificateRequest("www.other.com")},
+			expectedDenied:  true,
+			expectedMessage: "Denied by the approval policy of issuer1: DNS name www.other.com is not allowed",
+		},
+		"pending-invalid-policy": {
+			objects: []client.Object{
+				issuer(&issuerapi.ApprovalPolicy{AllowedIPRanges: []string{"10.0.0.0"}}),
+				cmgen.CertificateRequestFrom(certificateRequest("www.example.com"),
+					cmgen.SetCertificateRequestCSR(generateCSR(t, &x509.CertificateRequest{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}})),
+				),
+			},
+			expectedError: true,
+			expectedEvent: "InvalidApprovalPolicy",
+		},
+		"pending-invalid-policy-without-ip-addresses": {
+			objects: []client.Object{
+				issuer(&issuerapi.ApprovalPolicy{AllowedDNSNames: []string{"*.example.com"}, AllowedIPRanges: []string{"10.0.0.0"}}),
+				certificateRequest("www.example.com"),
+			},
+			expectedError: true,
+			expectedEvent: "InvalidApprovalPolicy",
+		},
+		"ignored-issuer-not-found": {
+			objects: []client.Object{certificateRequest("www.other.com")},
+		},
+		"ignored-without-policy": {
+			objects: []client.Object{issuer(nil), certificateRequest("www.other.com")},
+		},
+		"ignored-already-approved": {
+			objects: []client.Object{issuer(policy), certificateRequest("www.other.com",
+				cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+					Type:   cmapi.CertificateRequestConditionApproved,
+					Status: cmmeta.ConditionTrue,
+					Reason: "cert-manager.io",
+				}),
+			)},
+			expectedApproved: true,
+		},
+		"ignored-other-group": {
+			objects: []client.Object{issuer(policy), certificateRequest("www.other.com",
+				cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+					Name:  "issuer1",
+					Group: "cert-manager.io",
+					Kind:  "AWSPCAIssuer",
+				}),
+			)},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(tc.objects...).
+				WithStatusSubresource(tc.objects...).
+				Build()
+
+			recorder := record.NewFakeRecorder(10)
+			approver := CertificateRequestApprover{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: recorder,
+			}
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+			_, err := approver.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+			if tc.expectedError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			if tc.expectedEvent != "" {
+				assertEventRecorded(t, recorder, tc.expectedEvent)
+			}
+
+			cr := new(cmapi.CertificateRequest)
+			require.NoError(t, fakeClient.Get(ctx, name, cr))
+			assert.Equal(t, tc.expectedApproved, cmutil.CertificateRequestIsApproved(cr), "unexpected Approved condition")
+			assert.Equal(t, tc.expectedDenied, cmutil.CertificateRequestIsDenied(cr), "unexpected Denied condition")
+			if tc.expectedMessage != "" {
+				conditionType := cmapi.CertificateRequestConditionApproved
+				if tc.expectedDenied {
+					conditionType = cmapi.CertificateRequestConditionDenied
+				}
+				condition := cmutil.GetCertificateRequestCondition(cr, conditionType)
+				require.NotNil(t, condition)
+				assert.Equal(t, ApprovalReason, condition.Reason)
+				assert.Equal(t, tc.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestCertificateRequestApproverRequestsForIssuer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	request := func(name string, mods ...cmgen.CertificateRequestModifier) client.Object {
+		mods = append([]cmgen.CertificateRequestModifier{
+			cmgen.SetCertificateRequestNamespace("ns1"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "issuer1",
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  "AWSPCAIssuer",
+			}),
+		}, mods...)
+		return cmgen.CertificateRequest(name, mods...)
+	}
+	condition := func(conditionType cmapi.CertificateRequestConditionType) cmgen.CertificateRequestModifier {
+		return cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+			Type:   conditionType,
+			Status: cmmeta.ConditionTrue,
+		})
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			request("pending"),
+			request("approved", condition(cmapi.CertificateRequestConditionApproved)),
+			request("denied", condition(cmapi.CertificateRequestConditionDenied)),
+		).
+		WithIndex(&cmapi.CertificateRequest{}, issuerRefIndex, indexIssuerRef).
+		Build()
+	r := &CertificateRequestApprover{Client: fakeClient, Log: logrtesting.NewTestLogger(t)}
+
+	issuer := &issuerapi.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "issuer1"}}
+	assert.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "pending"}},
+	}, r.requestsForIssuer(context.TODO(), issuer))
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"slices"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+// authorize returns an error if no authorization rule of the issuer allows
+// the requester of a CertificateRequest to use the template it is signed
+// with, or if an issuer of SVIDs is asked for another identity than the
+// requester's
+func authorize(issuer api.GenericIssuer, cr *cmapi.CertificateRequest) error {
+	spec := issuer.GetSpec()
+	if spec.SPIFFE != nil {
+		if _, err := awspca.SPIFFEID(spec.SPIFFE.TrustDomain, cr); err != nil {
+			return err
+		}
+	}
+	if len(spec.Authorization) == 0 {
+		return nil
+	}
+
+	var defaultTemplateName string
+	if spec.PCATemplate != nil {
+		defaultTemplateName = spec.PCATemplate.DefaultTemplateName
+	}
+	if defaultTemplateName == "" && spec.SPIFFE != nil {
+		defaultTemplateName = awspca.SPIFFETemplateName
+	}
+	template := awspca.TemplateName(cr.Spec, defaultTemplateName)
+
+	requesterAllowed := false
+	for _, rule := range spec.Authorization {
+		if !matchesRequester(rule, cr.Spec.Username, cr.Spec.Groups) {
+			continue
+		}
+		if len(rule.Templates) == 0 || slices.Contains(rule.Templates, template) {
+			return nil
+		}
+		requesterAllowed = true
+	}
+
+	if requesterAllowed {
+		return fmt.Errorf("requester %q is not allowed to use template %s of issuer %s", cr.Spec.Username, template, issuer.GetName())
+	}
+	return fmt.Errorf("requester %q is not allowed to use issuer %s", cr.Spec.Username, issuer.GetName())
+}
+
+// matchesRequester returns whether a rule matches a user and its groups
+func matchesRequester(rule api.AuthorizationRule, username string, groups []string) bool {
+	if len(rule.Users) == 0 && len(rule.Groups) == 0 && len(rule.ServiceAccounts) == 0 {
+		return true
+	}
+	if slices.Contains(rule.Users, username) {
+		return true
+	}
+	for _, group := range groups {
+		if slices.Contains(rule.Groups, group) {
+			return true
+		}
+	}
+	for _, sa := range rule.ServiceAccounts {
+		if username == awspca.ServiceAccountUsernamePrefix+sa.Namespace+":"+sa.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/x509"
+	"net/url"
+	"testing"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestAuthorize(t *testing.T) {
+	type testCase struct {
+		rules           []issuerapi.AuthorizationRule
+		defaultTemplate string
+		username        string
+		groups          []string
+		usages          []cmapi.KeyUsage
+		expectedError   string
+	}
+
+	tests := map[string]testCase{
+		"no-rules-allows-everyone": {
+			username: "alice",
+		},
+		"user-allowed": {
+			rules:    []issuerapi.AuthorizationRule{{Users: []string{"alice"}}},
+			username: "alice",
+		},
+		"user-denied": {
+			rules:         []issuerapi.AuthorizationRule{{Users: []string{"alice"}}},
+			username:      "bob",
+			expectedError: `requester "bob" is not allowed to use issuer issuer1`,
+		},
+		"group-allowed": {
+			rules:    []issuerapi.AuthorizationRule{{Groups: []string{"team-a"}}},
+			username: "bob",
+			groups:   []string{"system:authenticated", "team-a"},
+		},
+		"service-account-allowed": {
+			rules: []issuerapi.AuthorizationRule{{
+				ServiceAccounts: []issuerapi.ServiceAccountReference{{Namespace: "cert-manager", Name: "cert-manager"}},
+			}},
+			username: "system:serviceaccount:cert-manager:cert-manager",
+		},
+		"service-account-other-namespace-denied": {
+			rules: []issuerapi.AuthorizationRule{{
+				ServiceAccounts: []issuerapi.ServiceAccountReference{{Namespace: "cert-manager", Name: "cert-manager"}},
+			}},
+			username:      "system:serviceaccount:other:cert-manager",
+			expectedError: `requester "system:serviceaccount:other:cert-manager" is not allowed to use issuer issuer1`,
+		},
+		"template-allowed": {
+			rules:    []issuerapi.AuthorizationRule{{Users: []string{"alice"}, Templates: []string{"EndEntityServerAuthCertificate/V1"}}},
+			username: "alice",
+			usages:   []cmapi.KeyUsage{cmapi.UsageServerAuth},
+		},
+		"template-denied": {
+			rules:         []issuerapi.AuthorizationRule{{Users: []string{"alice"}, Templates: []string{"EndEntityServerAuthCertificate/V1"}}},
+			username:      "alice",
+			usages:        []cmapi.KeyUsage{cmapi.UsageCodeSigning},
+			expectedError: `requester "alice" is not allowed to use template CodeSigningCertificate/V1 of issuer issuer1`,
+		},
+		"default-template-checked": {
+			rules:           []issuerapi.AuthorizationRule{{Templates: []string{"EndEntityCertificate/V1"}}},
+			defaultTemplate: "BlankEndEntityCertificate_APIPassthrough/V1",
+			username:        "alice",
+			expectedError:   `requester "alice" is not allowed to use template BlankEndEntityCertificate_APIPassthrough/V1 of issuer issuer1`,
+		},
+		"second-rule-allows-template": {
+			rules: []issuerapi.AuthorizationRule{
+				{Groups: []string{"team-a"}, Templates: []string{"EndEntityServerAuthCertificate/V1"}},
+				{Users: []string{"alice"}},
+			},
+			username: "alice",
+			groups:   []string{"team-a"},
+			usages:   []cmapi.KeyUsage{cmapi.UsageCodeSigning},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuer := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+				Spec:       issuerapi.AWSPCAIssuerSpec{Authorization: tc.rules},
+			}
+			if tc.defaultTemplate != "" {
+				issuer.Spec.PCATemplate = &issuerapi.PCATemplate{DefaultTemplateName: tc.defaultTemplate}
+			}
+			cr := &cmapi.CertificateRequest{
+				Spec: cmapi.CertificateRequestSpec{Username: tc.username, Groups: tc.groups, Usages: tc.usages},
+			}
+
+			err := authorize(issuer, cr)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeSPIFFE(t *testing.T) {
+	id, err := url.Parse("spiffe://cluster.local/ns/team-a/sa/app")
+	require.NoError(t, err)
+	issuer := &issuerapi.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "team-a"},
+		Spec: issuerapi.AWSPCAIssuerSpec{
+			SPIFFE: &issuerapi.SPIFFE{TrustDomain: "cluster.local"},
+			Authorization: []issuerapi.AuthorizationRule{
+				{Templates: []string{awspca.SPIFFETemplateName}},
+			},
+		},
+	}
+	cr := &cmapi.CertificateRequest{
+		Spec: cmapi.CertificateRequestSpec{
+			Request:  generateCSR(t, &x509.CertificateRequest{URIs: []*url.URL{id}}),
+			Username: "system:serviceaccount:team-a:app",
+		},
+	}
+	assert.NoError(t, authorize(issuer, cr))
+
+	cr.Spec.Username = "system:serviceaccount:team-a:other"
+	assert.ErrorIs(t, authorize(issuer, cr), awspca.ErrInvalidSVIDRequest)
+}
@@ -20,11 +20,14 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
 )
 
 // AWSPCAClusterIssuerReconciler reconciles a AWSPCAClusterIssuer object
@@ -50,8 +53,17 @@ func (r *AWSPCAClusterIssuerReconciler) Reconcile(ctx context.Context, req ctrl.
 	log := r.Log.WithValues("awspcaclusterissuer", req.NamespacedName)
 	iss := new(api.AWSPCAClusterIssuer)
 	if err := r.Client.Get(ctx, req.NamespacedName, iss); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.GenericController.forgetIssuer("AWSPCAClusterIssuer", req.NamespacedName)
+			awspca.DeleteProvisioner(ctx, r.Client, req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
 		log.Error(err, "Failed to request AWSPCAClusterIssuer")
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return ctrl.Result{}, err
+	}
+
+	if !iss.GetDeletionTimestamp().IsZero() {
+		return r.GenericController.finalize(ctx, req, iss)
 	}
 
 	return r.GenericController.Reconcile(ctx, req, iss)
@@ -60,6 +72,6 @@ func (r *AWSPCAClusterIssuerReconciler) Reconcile(ctx context.Context, req ctrl.
 // SetupWithManager sets up the controller with the Manager.
 func (r *AWSPCAClusterIssuerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&api.AWSPCAClusterIssuer{}).
+		For(&api.AWSPCAClusterIssuer{}, builder.WithPredicates(r.GenericController.IssuerFilter.Predicate())).
 		Complete(r)
 }
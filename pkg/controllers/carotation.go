@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var errNoCACertificate = errors.New("no CA certificate returned by PCA")
+
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates/status,verbs=get;update;patch
+
+// caCertificateFingerprint returns the hex encoded SHA-256 fingerprint of the
+// first certificate in a PEM bundle.
+func caCertificateFingerprint(bundle []byte) (string, error) {
+	block, _ := pem.Decode(bundle)
+	if block == nil {
+		return "", errNoCACertificate
+	}
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// trackCACertificate records the fingerprint of the CA certificate in the
+// issuer status. When it differs from the previously observed fingerprint an
+// event and condition are emitted and, if enabled, Certificates issued under
+// the previous CA certificate are re-issued.
+func (r *GenericIssuerReconciler) trackCACertificate(ctx context.Context, issuer api.GenericIssuer, bundle []byte) error {
+	fingerprint, err := caCertificateFingerprint(bundle)
+	if err != nil {
+		return err
+	}
+
+	status := issuer.GetStatus()
+	previous := status.CACertificateFingerprint
+	if previous == fingerprint {
+		return nil
+	}
+
+	if previous != "" {
+		message := fmt.Sprintf("CA certificate changed from %s to %s", previous, fingerprint)
+		r.Recorder.Event(issuer, core.EventTypeWarning, "CACertificateRotated", message)
+		util.SetIssuerCondition(r.Log, issuer, api.ConditionTypeCACertificateRotated, metav1.ConditionTrue, "Rotated", message)
+
+		if issuer.GetSpec().ReissueOnCARotation {
+			if err := r.reissueCertificates(ctx, issuer, fingerprint); err != nil {
+				return err
+			}
+		}
+	}
+
+	status.CACertificateFingerprint = fingerprint
+	return r.Client.Status().Update(ctx, issuer)
+}
+
+// reissueCertificates annotates the CertificateRequests of the issuer that
+// were issued under another CA certificate, and triggers the re-issuance of
+// the Certificates they are the current revision of.
+func (r *GenericIssuerReconciler) reissueCertificates(ctx context.Context, issuer api.GenericIssuer, fingerprint string) error {
+	crList := new(cmapi.CertificateRequestList)
+	var opts []client.ListOption
+	if !isClusterIssuer(issuer) {
+		opts = append(opts, client.InNamespace(issuer.GetNamespace()))
+	}
+	if err := r.Client.List(ctx, crList, opts...); err != nil {
+		return fmt.Errorf("failed to list CertificateRequests: %w", err)
+	}
+
+	for i := range crList.Items {
+		cr := &crList.Items[i]
+		if !issuedBy(cr, issuer) {
+			continue
+		}
+		annotations := cr.GetAnnotations()
+		issuedUnder, ok := annotations[awspca.CACertificateFingerprintAnnotation]
+		if !ok || issuedUnder == fingerprint {
+			continue
+		}
+		if _, rotated := annotations[awspca.CARotatedAnnotation]; rotated {
+			continue
+		}
+
+		if err := r.reissueCertificate(ctx, cr, fingerprint); err != nil {
+			return err
+		}
+
+		base := cr.DeepCopy()
+		metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.CARotatedAnnotation, fingerprint)
+		if err := r.Client.Patch(ctx, cr, client.MergeFrom(base)); err != nil {
+			return fmt.Errorf("failed to annotate CertificateRequest %s/%s: %w", cr.Namespace, cr.Name, err)
+		}
+	}
+	return nil
+}
+
+// reissueCertificate marks the Certificate owning cr as Issuing, as `cmctl
+// renew` does, if cr is its current revision.
+func (r *GenericIssuerReconciler) reissueCertificate(ctx context.Context, cr *cmapi.CertificateRequest, fingerprint string) error {
+	annotations := cr.GetAnnotations()
+	name, ok := annotations[cmapi.CertificateNameKey]
+	if !ok {
+		return nil
+	}
+
+	crt := new(cmapi.Certificate)
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: name}, crt); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get Certificate %s/%s: %w", cr.Namespace, name, err)
+	}
+
+	if crt.Status.Revision == nil || annotations[cmapi.CertificateRequestRevisionAnnotationKey] != fmt.Sprint(*crt.Status.Revision) {
+		return nil
+	}
+
+	base := crt.DeepCopy()
+	metav1.SetMetaDataAnnotation(&crt.ObjectMeta, awspca.CARotatedAnnotation, fingerprint)
+	if err := r.Client.Patch(ctx, crt, client.MergeFrom(base)); err != nil {
+		return fmt.Errorf("failed to annotate Certificate %s/%s: %w", crt.Namespace, crt.Name, err)
+	}
+
+	if cmutil.CertificateHasCondition(crt, cmapi.CertificateCondition{
+		Type:   cmapi.CertificateConditionIssuing,
+		Status: cmmeta.ConditionTrue,
+	}) {
+		return nil
+	}
+	cmutil.SetCertificateCondition(crt, crt.Generation, cmapi.CertificateConditionIssuing, cmmeta.ConditionTrue,
+		"CACertificateRotated", "Re-issuing certificate as the CA certificate of the issuer changed")
+	if err := r.Client.Status().Update(ctx, crt); err != nil {
+		return fmt.Errorf("failed to re-issue Certificate %s/%s: %w", crt.Namespace, crt.Name, err)
+	}
+	return nil
+}
+
+// issuedBy returns whether the issuerRef of cr refers to issuer
+func issuedBy(cr *cmapi.CertificateRequest, issuer api.GenericIssuer) bool {
+	ref := cr.Spec.IssuerRef
+	if ref.Group != api.GroupVersion.Group || ref.Name != issuer.GetName() {
+		return false
+	}
+	return ref.Kind == issuerKind(issuer)
+}
@@ -20,22 +20,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/audit"
 	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/sharding"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/tracing"
 	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"go.opentelemetry.io/otel/attribute"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/go-logr/logr"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
 	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
@@ -51,6 +62,17 @@ type CertificateRequestReconciler struct {
 
 	Clock                  clock.Clock
 	CheckApprovedCondition bool
+	// IssuerFilter selects the issuers whose CertificateRequests are signed
+	// by this instance
+	IssuerFilter IssuerFilter
+	// Audit, if set, records every decision on a CertificateRequest
+	Audit *audit.Logger
+	// Sharder, if set, restricts this replica to the CertificateRequests of
+	// the shards it holds, and runs the controller without leader election
+	Sharder *sharding.Sharder
+	// Pollers, if set, polls the certificates of the provisioners, and the
+	// CertificateRequests are reconciled again once theirs was polled
+	Pollers *awspca.Pollers
 }
 
 // We put this in a variable to easily mock it
@@ -58,7 +80,11 @@ var (
 	GetProvisioner = awspca.GetProvisioner
 )
 
-// +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;update
+// pendingRequeueAfter is how long a CertificateRequest waits for the poller
+// of its CA before it is reconciled again regardless
+const pendingRequeueAfter = time.Minute
+
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
@@ -68,8 +94,19 @@ var (
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
-func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "CertificateRequest.Reconcile",
+		attribute.String("certificaterequest.namespace", req.Namespace),
+		attribute.String("certificaterequest.name", req.Name),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	log := r.Log.WithValues("certificaterequest", req.NamespacedName)
+	if r.Sharder != nil && !r.Sharder.Owns(req.NamespacedName) {
+		log.V(4).Info("CertificateRequest belongs to a shard held by another replica. Ignoring.")
+		return ctrl.Result{}, nil
+	}
+
 	cr := new(cmapi.CertificateRequest)
 	if err := r.Client.Get(ctx, req.NamespacedName, cr); err != nil {
 		if apierrors.IsNotFound(err) {
@@ -123,6 +160,7 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 		}
 
 		message := "The CertificateRequest was denied by an approval controller"
+		r.audit(cr, nil, audit.DecisionDenied, message)
 		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonDenied, message)
 	}
 
@@ -143,64 +181,148 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 		Namespace: cr.Namespace,
 		Name:      cr.Spec.IssuerRef.Name,
 	}
-	if cr.Spec.IssuerRef.Kind == "AWSPCAClusterIssuer" {
+	if cr.Spec.IssuerRef.Kind == util.ClusterIssuerKind {
 		issuerName.Namespace = ""
 	}
 
-	iss, err := util.GetIssuer(ctx, r.Client, issuerName)
+	iss, err := util.GetIssuer(ctx, r.Client, cr.Spec.IssuerRef.Kind, issuerName)
 	if err != nil {
+		if errors.Is(err, util.ErrUnknownIssuerKind) {
+			log.Info("CertificateRequest refers to an unknown issuer kind", "kind", cr.Spec.IssuerRef.Kind)
+			if cr.Status.FailureTime == nil {
+				nowTime := metav1.NewTime(r.Clock.Now())
+				cr.Status.FailureTime = &nowTime
+			}
+			r.audit(cr, nil, audit.DecisionFailed, err.Error())
+			return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, err.Error())
+		}
+
 		log.Error(err, "failed to retrieve Issuer resource")
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "issuer could not be found")
+		message := "issuer could not be found"
+		if !apierrors.IsNotFound(err) {
+			message = "failed to retrieve issuer: " + err.Error()
+		}
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, message)
 		return ctrl.Result{}, err
 	}
 
+	if !r.IssuerFilter.Matches(iss) {
+		log.V(4).Info("CertificateRequest refers to an issuer handled by another instance")
+		return ctrl.Result{}, nil
+	}
+
 	if !isReady(iss) {
 		err := fmt.Errorf("issuer %s is not ready", iss.GetName())
 		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "issuer is not ready")
 		return ctrl.Result{}, err
 	}
 
+	// Requests already submitted to PCA are not re-evaluated, so that a
+	// change to the rules cannot strand an issued certificate
+	if _, submitted := cr.GetAnnotations()[awspca.CertificateArnAnnotation]; !submitted {
+		if err := authorize(iss, cr); err != nil {
+			log.Info("CertificateRequest is not authorized", "reason", err.Error())
+			if cr.Status.FailureTime == nil {
+				nowTime := metav1.NewTime(r.Clock.Now())
+				cr.Status.FailureTime = &nowTime
+			}
+			r.audit(cr, iss, audit.DecisionDenied, err.Error())
+			return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonDenied, err.Error())
+		}
+	}
+
 	provisioner, err := GetProvisioner(ctx, r.Client, issuerName, iss.GetSpec())
 	if err != nil {
 		log.Error(err, "failed to retrieve provisioner")
+		r.audit(cr, iss, audit.DecisionFailed, "failed to retrieve provisioner")
 		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "failed to retrieve provisioner")
 		return ctrl.Result{}, err
 	}
 
-	certArn, exists := cr.ObjectMeta.GetAnnotations()["aws-privateca-issuer/certificate-arn"]
+	certArn, exists := cr.ObjectMeta.GetAnnotations()[awspca.CertificateArnAnnotation]
 	if !exists {
 		var pcaTemplateName string
 		if iss.GetSpec().PCATemplate != nil {
 			pcaTemplateName = iss.GetSpec().PCATemplate.DefaultTemplateName
 		}
+		if isDryRun(iss, cr) {
+			return ctrl.Result{}, r.dryRun(ctx, cr, iss, provisioner, pcaTemplateName, log)
+		}
+
+		if err := r.recordIssuanceStart(ctx, cr, log); err != nil {
+			log.Error(err, "failed to record idempotency token on CertificateRequest")
+			return ctrl.Result{}, err
+		}
+
+		base := cr.DeepCopy()
 		err := provisioner.Sign(ctx, cr, pcaTemplateName, log)
 		if err != nil {
 			log.Error(err, "failed to request certificate from PCA")
-			return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "failed to request certificate from PCA: "+err.Error())
+			message := "failed to request certificate from PCA: " + err.Error()
+			r.audit(cr, iss, audit.DecisionFailed, message)
+			return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, message)
 		}
 
-		err = r.Client.Update(ctx, cr)
-		if err != nil {
-			if apierrors.IsConflict(err) {
-				log.Info("conflict updating CertificateRequest, will requeue")
-				return ctrl.Result{Requeue: true}, nil
-			}
+		// A merge patch of the annotations cannot conflict with concurrent
+		// changes to the CertificateRequest, and is retried on other errors.
+		// Should it fail anyway, signing again returns the ARN of the
+		// certificate that was just issued.
+		if err := retry.OnError(retry.DefaultBackoff, isRetriablePatchError, func() error {
+			return r.Client.Patch(ctx, cr, client.MergeFrom(base))
+		}); err != nil {
+			log.Error(err, "failed to record certificate ARN on CertificateRequest")
 			return ctrl.Result{}, err
 		}
+		r.audit(cr, iss, audit.DecisionApproved, "certificate requested from PCA")
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	base := cr.DeepCopy()
 	pem, ca, err := provisioner.Get(ctx, cr, certArn, log)
 	if err != nil {
+		if errors.Is(err, awspca.ErrCertificatePending) {
+			log.V(4).Info("waiting for the certificate to be polled")
+			return ctrl.Result{RequeueAfter: pendingRequeueAfter}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "waiting for certificate to be issued")
+		}
+
 		var errorType *acmpcatypes.RequestInProgressException
 		if errors.As(err, &errorType) {
 			log.Info("certificate is still issuing")
 			return ctrl.Result{Requeue: true}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "waiting for certificate to be issued")
 		}
 
+		if errors.Is(err, awspca.ErrIdempotencyMismatch) {
+			token := cr.GetAnnotations()[awspca.IdempotencyTokenAnnotation]
+			log.Error(err, "PCA returned the certificate of an earlier request", "idempotencyToken", token)
+			r.Recorder.Eventf(cr, core.EventTypeWarning, "IdempotencyMismatch",
+				"PCA returned certificate %s for idempotency token %s, which was issued for another CSR", certArn, token)
+		}
+
+		if errors.Is(err, awspca.ErrCertificateMismatch) {
+			log.Error(err, "certificate returned by PCA does not match the CertificateRequest")
+			message := "rejected certificate returned by PCA: " + err.Error()
+			r.audit(cr, iss, audit.DecisionFailed, message)
+			return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, message)
+		}
+
 		log.Error(err, "failed to issue certificate from PCA")
-		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "failed to issue certificate from PCA: "+err.Error())
+		message := "failed to issue certificate from PCA: " + err.Error()
+		r.audit(cr, iss, audit.DecisionFailed, message)
+		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, message)
+	}
+
+	if fingerprint := iss.GetStatus().CACertificateFingerprint; fingerprint != "" {
+		metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.CACertificateFingerprintAnnotation, fingerprint)
+	}
+
+	// Persist the metadata of the issued certificate before the status is
+	// written, as the patch response replaces the in-memory object.
+	if err := r.Client.Patch(ctx, cr, client.MergeFrom(base)); err != nil {
+		log.Error(err, "failed to record certificate metadata on CertificateRequest")
+		return ctrl.Result{}, err
 	}
+	r.recordIssuanceDetails(cr)
+	r.audit(cr, iss, audit.DecisionIssued, "certificate issued")
 
 	cr.Status.Certificate = pem
 	cr.Status.CA = ca
@@ -209,9 +331,24 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *CertificateRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &cmapi.CertificateRequest{}, issuerRefIndex, indexIssuerRef); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&cmapi.CertificateRequest{}).
-		Complete(r)
+		Watches(&api.AWSPCAIssuer{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIssuer), builder.WithPredicates(issuerBecameReady, r.IssuerFilter.Predicate())).
+		Watches(&api.AWSPCAClusterIssuer{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIssuer), builder.WithPredicates(issuerBecameReady, r.IssuerFilter.Predicate()))
+	if r.Pollers != nil {
+		b = b.WatchesRawSource(source.Channel(r.Pollers.Completed(), &handler.EnqueueRequestForObject{}))
+	}
+	if r.Sharder != nil {
+		b = b.WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+			WatchesRawSource(source.Channel(r.Sharder.Gained(), handler.EnqueueRequestsFromMapFunc(r.requestsForShard))).
+			Watches(&api.AWSPCAIssuer{}, r.refreshProvisioners()).
+			Watches(&api.AWSPCAClusterIssuer{}, r.refreshProvisioners())
+	}
+	return b.Complete(r)
 }
 
 func isReady(issuer api.GenericIssuer) bool {
@@ -223,6 +360,80 @@ func isReady(issuer api.GenericIssuer) bool {
 	return false
 }
 
+// recordIssuanceStart records the idempotency token of a CertificateRequest,
+// and when it was first submitted to PCA, before it is submitted. A
+// CertificateRequest whose certificate ARN could not be recorded is then known
+// to have been submitted, even after a restart, a leader failover or a shard
+// handover. Signing it again returns the same certificate within PCA's
+// idempotency window; after it, a warning is emitted unless the ARN is still
+// remembered by this process, as PCA issues a second certificate.
+func (r *CertificateRequestReconciler) recordIssuanceStart(ctx context.Context, cr *cmapi.CertificateRequest, log logr.Logger) error {
+	token := awspca.IdempotencyToken(cr)
+	annotations := cr.GetAnnotations()
+	if annotations[awspca.IdempotencyTokenAnnotation] == token {
+		started, err := time.Parse(time.RFC3339, annotations[awspca.IssuanceStartedAnnotation])
+		if err == nil && r.Clock.Since(started) > awspca.IdempotencyWindow && !awspca.IssuanceRecorded(token, r.Clock.Now()) {
+			log.Info("signing CertificateRequest again after its certificate ARN was lost", "idempotencyToken", token, "started", started)
+			r.Recorder.Eventf(cr, core.EventTypeWarning, "DuplicateIssuance",
+				"CertificateRequest was submitted to PCA at %s, but its certificate ARN was not recorded. Signing it again issues a second certificate.",
+				started.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	base := cr.DeepCopy()
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.IdempotencyTokenAnnotation, token)
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.IssuanceStartedAnnotation, r.Clock.Now().UTC().Format(time.RFC3339))
+	return r.Client.Patch(ctx, cr, client.MergeFrom(base))
+}
+
+// isRetriablePatchError reports whether patching a CertificateRequest that
+// was just submitted to PCA is worth retrying. Only transient errors are
+// retried; a rejected patch fails the same way again.
+func isRetriablePatchError(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err)
+}
+
+// recordIssuanceDetails emits an event summarising the PCA artefacts behind
+// the issued certificate, so it can be correlated with CloudTrail.
+func (r *CertificateRequestReconciler) recordIssuanceDetails(cr *cmapi.CertificateRequest) {
+	annotations := cr.GetAnnotations()
+	r.Recorder.Eventf(cr, core.EventTypeNormal, "IssuanceDetails",
+		"Certificate %s with serial %s issued by %s using template %s and signing algorithm %s, valid from %s to %s (idempotency token %s)",
+		annotations[awspca.CertificateArnAnnotation],
+		annotations[awspca.SerialNumberAnnotation],
+		annotations[awspca.CertificateAuthorityArnAnnotation],
+		annotations[awspca.TemplateArnAnnotation],
+		annotations[awspca.SigningAlgorithmAnnotation],
+		annotations[awspca.NotBeforeAnnotation],
+		annotations[awspca.NotAfterAnnotation],
+		annotations[awspca.IdempotencyTokenAnnotation],
+	)
+}
+
+// audit records a decision on a CertificateRequest in the audit log, along
+// with the PCA artefacts recorded on it so far
+func (r *CertificateRequestReconciler) audit(cr *cmapi.CertificateRequest, iss api.GenericIssuer, decision audit.Decision, reason string) {
+	record := audit.NewRecord(cr, decision, reason)
+	if iss != nil {
+		record.CAArn = iss.GetSpec().Arn
+	}
+	annotations := cr.GetAnnotations()
+	if caArn := annotations[awspca.CertificateAuthorityArnAnnotation]; caArn != "" {
+		record.CAArn = caArn
+	}
+	record.Template = annotations[awspca.TemplateArnAnnotation]
+	record.SerialNumber = annotations[awspca.SerialNumberAnnotation]
+	record.CertificateArn = annotations[awspca.CertificateArnAnnotation]
+
+	if err := r.Audit.Log(record); err != nil {
+		r.Log.Error(err, "failed to write audit record", "certificaterequest", client.ObjectKeyFromObject(cr), "decision", decision)
+	}
+}
+
 func (r *CertificateRequestReconciler) setStatus(ctx context.Context, cr *cmapi.CertificateRequest, status cmmeta.ConditionStatus, reason, message string) error {
 	cmutil.SetCertificateRequestCondition(cr, "Ready", status, reason, message)
 
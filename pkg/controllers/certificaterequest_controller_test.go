@@ -15,10 +15,14 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
 	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
@@ -36,6 +40,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -43,6 +48,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/audit"
 	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
 )
 
@@ -51,6 +57,10 @@ type fakeProvisioner struct {
 	caCert          []byte
 	getErr          error
 	signErr         error
+	caBundle        []byte
+	caBundleErr     error
+	ca              *awspca.CertificateAuthority
+	describeErr     error
 	pcaTemplateName string
 }
 
@@ -60,10 +70,27 @@ func (p *fakeProvisioner) Sign(ctx context.Context, cr *cmapi.CertificateRequest
 	return p.signErr
 }
 
+func (p *fakeProvisioner) DryRun(ctx context.Context, cr *cmapi.CertificateRequest, pcaTemplateName string, log logr.Logger) error {
+	p.pcaTemplateName = pcaTemplateName
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.DryRunInputAnnotation, "{}")
+	return p.signErr
+}
+
 func (p *fakeProvisioner) Get(ctx context.Context, cr *cmapi.CertificateRequest, certArn string, log logr.Logger) ([]byte, []byte, error) {
+	if p.getErr == nil {
+		metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.SerialNumberAnnotation, "01")
+	}
 	return p.cert, p.caCert, p.getErr
 }
 
+func (p *fakeProvisioner) GetCACertificate(ctx context.Context) ([]byte, error) {
+	return p.caBundle, p.caBundleErr
+}
+
+func (p *fakeProvisioner) DescribeCertificateAuthority(ctx context.Context) (*awspca.CertificateAuthority, error) {
+	return p.ca, p.describeErr
+}
+
 func generateMockGetProvisioner(p *fakeProvisioner, err error) func(context.Context, client.Client, types.NamespacedName, *issuerapi.AWSPCAIssuerSpec) (awspca.GenericProvisioner, error) {
 	return func(_ context.Context, _ client.Client, name types.NamespacedName, _ *issuerapi.AWSPCAIssuerSpec) (awspca.GenericProvisioner, error) {
 		return p, err
@@ -82,6 +109,9 @@ func TestCertificateRequestReconcile(t *testing.T) {
 		expectedCertificate          []byte
 		expectedCACertificate        []byte
 		expectedTemplate             string
+		expectedAnnotations          map[string]string
+		expectedEventReason          string
+		expectedAuditDecisions       []audit.Decision
 		mockProvisioner              func(context.Context, client.Client, types.NamespacedName, *issuerapi.AWSPCAIssuerSpec) (awspca.GenericProvisioner, error)
 	}
 	tests := map[string]testCase{
@@ -94,7 +124,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "issuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "Issuer",
+						Kind:  "AWSPCAIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -123,6 +153,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 								Status: metav1.ConditionTrue,
 							},
 						},
+						CACertificateFingerprint: "0123456789abcdef",
 					},
 				},
 				&v1.Secret{
@@ -143,7 +174,56 @@ func TestCertificateRequestReconcile(t *testing.T) {
 			expectedError:                false,
 			expectedCertificate:          []byte("cert"),
 			expectedCACertificate:        []byte("cacert"),
-			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{caCert: []byte("cacert"), cert: []byte("cert")}, nil),
+			expectedAnnotations: map[string]string{
+				awspca.CertificateArnAnnotation:           "arn",
+				awspca.SerialNumberAnnotation:             "01",
+				awspca.CACertificateFingerprintAnnotation: "0123456789abcdef",
+			},
+			expectedAuditDecisions: []audit.Decision{audit.DecisionApproved, audit.DecisionIssued},
+			mockProvisioner:        generateMockGetProvisioner(&fakeProvisioner{caCert: []byte("cacert"), cert: []byte("cert")}, nil),
+		},
+		"ignored-issuer-other-instance": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "issuer1",
+						Namespace:   "ns1",
+						Annotations: map[string]string{InstanceAnnotation: "other"},
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			},
+			expectedSignResult: ctrl.Result{},
+			expectedGetResult:  ctrl.Result{},
+			expectedAnnotations: map[string]string{
+				awspca.CertificateArnAnnotation: "",
+			},
+			mockProvisioner: generateMockGetProvisioner(&fakeProvisioner{caCert: []byte("cacert"), cert: []byte("cert")}, nil),
 		},
 		"success-cluster-issuer": {
 			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
@@ -154,7 +234,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "clusterissuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "ClusterIssuer",
+						Kind:  "AWSPCAClusterIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -211,7 +291,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "clusterissuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "ClusterIssuer",
+						Kind:  "AWSPCAClusterIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -273,7 +353,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "clusterissuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "ClusterIssuer",
+						Kind:  "AWSPCAClusterIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -331,7 +411,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "issuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "Issuer",
+						Kind:  "AWSPCAIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -377,7 +457,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "issuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "Issuer",
+						Kind:  "AWSPCAIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -426,7 +506,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 			expectedError:                false,
 			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{getErr: &acmpcatypes.RequestInProgressException{}}, nil),
 		},
-		"failure-get-failure": {
+		"failure-certificate-pending": {
 			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
 			objects: []client.Object{
 				cmgen.CertificateRequest(
@@ -435,7 +515,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "issuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "Issuer",
+						Kind:  "AWSPCAIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -478,12 +558,13 @@ func TestCertificateRequestReconcile(t *testing.T) {
 				},
 			},
 			expectedSignResult:           ctrl.Result{Requeue: true},
+			expectedGetResult:            ctrl.Result{RequeueAfter: pendingRequeueAfter},
 			expectedReadyConditionStatus: cmmeta.ConditionFalse,
-			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonPending,
 			expectedError:                false,
-			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{getErr: errors.New("Get failure")}, nil),
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{getErr: awspca.ErrCertificatePending}, nil),
 		},
-		"pending-issuer-not-ready": {
+		"failure-get-failure": {
 			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
 			objects: []client.Object{
 				cmgen.CertificateRequest(
@@ -492,7 +573,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "issuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "Issuer",
+						Kind:  "AWSPCAIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -518,7 +599,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 						Conditions: []metav1.Condition{
 							{
 								Type:   issuerapi.ConditionTypeReady,
-								Status: metav1.ConditionFalse,
+								Status: metav1.ConditionTrue,
 							},
 						},
 					},
@@ -534,11 +615,13 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					},
 				},
 			},
+			expectedSignResult:           ctrl.Result{Requeue: true},
 			expectedReadyConditionStatus: cmmeta.ConditionFalse,
-			expectedReadyConditionReason: cmapi.CertificateRequestReasonPending,
-			expectedError:                true,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
+			expectedError:                false,
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{getErr: errors.New("Get failure")}, nil),
 		},
-		"pending-issuer-not-found": {
+		"failure-certificate-mismatch": {
 			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
 			objects: []client.Object{
 				cmgen.CertificateRequest(
@@ -547,13 +630,37 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "issuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "Issuer",
+						Kind:  "AWSPCAIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
 						Status: cmmeta.ConditionUnknown,
 					}),
 				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
 				&v1.Secret{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "issuer1-credentials",
@@ -565,11 +672,14 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					},
 				},
 			},
+			expectedSignResult:           ctrl.Result{Requeue: true},
 			expectedReadyConditionStatus: cmmeta.ConditionFalse,
-			expectedReadyConditionReason: cmapi.CertificateRequestReasonPending,
-			expectedError:                true,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
+			expectedError:                false,
+			expectedAuditDecisions:       []audit.Decision{audit.DecisionApproved, audit.DecisionFailed},
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{getErr: fmt.Errorf("%w: public key differs from the CSR", awspca.ErrCertificateMismatch)}, nil),
 		},
-		"failure-sign-failure": {
+		"failure-idempotency-mismatch": {
 			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
 			objects: []client.Object{
 				cmgen.CertificateRequest(
@@ -578,7 +688,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "issuer1",
 						Group: issuerapi.GroupVersion.Group,
-						Kind:  "Issuer",
+						Kind:  "AWSPCAIssuer",
 					}),
 					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
 						Type:   cmapi.CertificateRequestConditionReady,
@@ -620,149 +730,799 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					},
 				},
 			},
+			expectedSignResult:           ctrl.Result{Requeue: true},
 			expectedReadyConditionStatus: cmmeta.ConditionFalse,
 			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
 			expectedError:                false,
-			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{signErr: errors.New("Sign Failure")}, nil),
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{getErr: fmt.Errorf("%w: public key differs from the CSR: %w", awspca.ErrCertificateMismatch, awspca.ErrIdempotencyMismatch)}, nil),
+			expectedEventReason:          "IdempotencyMismatch",
 		},
-	}
-
-	scheme := runtime.NewScheme()
-	require.NoError(t, issuerapi.AddToScheme(scheme))
-	require.NoError(t, cmapi.AddToScheme(scheme))
-	require.NoError(t, v1.AddToScheme(scheme))
-
-	for name, tc := range tests {
-		t.Run(name, func(t *testing.T) {
-			fakeClient := fake.NewClientBuilder().
-				WithScheme(scheme).
-				WithObjects(tc.objects...).
-				WithStatusSubresource(tc.objects...).
-				Build()
-			controller := CertificateRequestReconciler{
-				Client:   fakeClient,
-				Log:      logrtesting.NewTestLogger(t),
-				Scheme:   scheme,
-				Recorder: record.NewFakeRecorder(10),
-			}
-
-			ctx := context.TODO()
-
-			if tc.mockProvisioner != nil {
-				GetProvisioner = tc.mockProvisioner
-				t.Cleanup(awspca.ClearProvisioners)
-			}
-
-			result, signErr := controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name})
-			assert.Equal(t, tc.expectedSignResult, result, "Unexpected sign result")
-
-			result, getErr := controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name})
-			assert.Equal(t, tc.expectedGetResult, result, "Unexpected get result")
-
-			if tc.expectedTemplate != "" {
-				p, _ := GetProvisioner(ctx, fakeClient, tc.name, nil)
-				assert.Equal(t, tc.expectedTemplate, p.(*fakeProvisioner).pcaTemplateName)
-			}
-
-			if tc.expectedError && (signErr == nil && getErr == nil) {
-				assert.Fail(t, "Expected an error but got none")
-			}
-
-			var cr cmapi.CertificateRequest
-			err := fakeClient.Get(ctx, tc.name, &cr)
-			require.NoError(t, err, "unexpected error from fake client")
-			if err == nil {
-				if tc.expectedReadyConditionStatus != "" {
-					assertCertificateRequestHasReadyCondition(t, tc.expectedReadyConditionStatus, tc.expectedReadyConditionReason, &cr)
-				}
-				if tc.expectedCertificate != nil {
-					assert.Equal(t, tc.expectedCertificate, cr.Status.Certificate)
-				}
-				if tc.expectedCACertificate != nil {
-					assert.Equal(t, tc.expectedCACertificate, cr.Status.CA)
-				}
-			}
-		})
-	}
-}
-
-func assertCertificateRequestHasReadyCondition(t *testing.T, status cmmeta.ConditionStatus, reason string, cr *cmapi.CertificateRequest) {
-	condition := cmutil.GetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionReady)
-	if !assert.NotNil(t, condition, "Ready condition not found") {
-		return
-	}
-	assert.Equal(t, status, condition.Status, "unexpected condition status")
-	validReasons := sets.NewString(
-		cmapi.CertificateRequestReasonFailed,
-		cmapi.CertificateRequestReasonIssued,
-		cmapi.CertificateRequestReasonPending,
-	)
-	assert.Contains(t, validReasons, reason, "unexpected condition reason")
-	assert.Equal(t, reason, condition.Reason, "unexpected condition reason")
-}
-
-func TestCertificateRequestReconcile_UpdateConflict(t *testing.T) {
-	scheme := runtime.NewScheme()
-	require.NoError(t, issuerapi.AddToScheme(scheme))
-	require.NoError(t, cmapi.AddToScheme(scheme))
-	require.NoError(t, v1.AddToScheme(scheme))
-
-	objects := []client.Object{
-		cmgen.CertificateRequest(
-			"cr1",
-			cmgen.SetCertificateRequestNamespace("ns1"),
-			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
-				Name:  "issuer1",
-				Group: issuerapi.GroupVersion.Group,
-				Kind:  "Issuer",
-			}),
-			cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
-				Type:   cmapi.CertificateRequestConditionReady,
-				Status: cmmeta.ConditionUnknown,
-			}),
-		),
-		&issuerapi.AWSPCAIssuer{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "issuer1",
-				Namespace: "ns1",
-			},
-			Spec: issuerapi.AWSPCAIssuerSpec{
-				Region: "us-east-1",
-				Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
-			},
-			Status: issuerapi.AWSPCAIssuerStatus{
-				Conditions: []metav1.Condition{
-					{Type: issuerapi.ConditionTypeReady, Status: metav1.ConditionTrue},
+		"pending-issuer-not-ready": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionFalse,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
 				},
 			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonPending,
+			expectedError:                true,
 		},
-	}
-
-	fakeClient := fake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(objects...).
-		WithStatusSubresource(objects...).
-		WithInterceptorFuncs(interceptor.Funcs{
-			Update: func(ctx context.Context, client client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
-				return apierrors.NewConflict(cmapi.Resource("certificaterequests"), "cr1", errors.New("conflict"))
-			},
-		}).
-		Build()
-
-	controller := CertificateRequestReconciler{
-		Client:   fakeClient,
-		Log:      logrtesting.NewTestLogger(t),
-		Scheme:   scheme,
-		Recorder: record.NewFakeRecorder(10),
-	}
-
-	GetProvisioner = generateMockGetProvisioner(&fakeProvisioner{cert: []byte("cert"), caCert: []byte("cacert")}, nil)
-	t.Cleanup(awspca.ClearProvisioners)
+		"pending-issuer-not-found": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonPending,
+			expectedError:                true,
+		},
+		"pending-namespaced-issuer-not-found-with-cluster-issuer": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "clusterissuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "clusterissuer1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name: "clusterissuer1-credentials",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "clusterissuer1-credentials",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonPending,
+			expectedError:                true,
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{caCert: []byte("cacert"), cert: []byte("cert")}, nil),
+		},
+		"failure-unknown-issuer-kind": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "clusterissuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "ClusterIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "clusterissuer1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name: "clusterissuer1-credentials",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "clusterissuer1-credentials",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
+			expectedError:                false,
+			expectedEventReason:          cmapi.CertificateRequestReasonFailed,
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{caCert: []byte("cacert"), cert: []byte("cert")}, nil),
+		},
+		"failure-sign-failure": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
+			expectedError:                false,
+			expectedAuditDecisions:       []audit.Decision{audit.DecisionFailed},
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{signErr: errors.New("Sign Failure")}, nil),
+		},
+		"denied-unauthorized": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Authorization: []issuerapi.AuthorizationRule{
+							{Users: []string{"alice"}},
+						},
+						Arn: "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonDenied,
+			expectedError:                false,
+			expectedEventReason:          cmapi.CertificateRequestReasonDenied,
+			expectedAuditDecisions:       []audit.Decision{audit.DecisionDenied},
+			mockProvisioner:              generateMockGetProvisioner(&fakeProvisioner{}, nil),
+		},
+		"dry-run-issuer": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						DryRun: true,
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
+			expectedError:                false,
+			expectedAnnotations: map[string]string{
+				awspca.DryRunInputAnnotation:    "{}",
+				awspca.CertificateArnAnnotation: "",
+			},
+			expectedEventReason:    "DryRun",
+			expectedAuditDecisions: []audit.Decision{audit.DecisionDryRun},
+			mockProvisioner:        generateMockGetProvisioner(&fakeProvisioner{}, nil),
+		},
+		"dry-run-annotation": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
+			objects: []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.AddCertificateRequestAnnotations(map[string]string{awspca.DryRunAnnotation: "true"}),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "AWSPCAIssuer",
+					}),
+					cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+						Type:   cmapi.CertificateRequestConditionReady,
+						Status: cmmeta.ConditionUnknown,
+					}),
+				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: cmmeta.ConditionFalse,
+			expectedReadyConditionReason: cmapi.CertificateRequestReasonFailed,
+			expectedError:                false,
+			expectedAnnotations: map[string]string{
+				awspca.DryRunInputAnnotation:    "{}",
+				awspca.CertificateArnAnnotation: "",
+			},
+			expectedEventReason:    "DryRun",
+			expectedAuditDecisions: []audit.Decision{audit.DecisionDryRun},
+			mockProvisioner:        generateMockGetProvisioner(&fakeProvisioner{}, nil),
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(tc.objects...).
+				WithStatusSubresource(tc.objects...).
+				Build()
+			recorder := record.NewFakeRecorder(10)
+			var auditLog bytes.Buffer
+			controller := CertificateRequestReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: recorder,
+				Clock:    clocktesting.NewFakeClock(time.Now()),
+				Audit:    audit.New(&auditLog),
+			}
+
+			ctx := context.TODO()
+
+			if tc.mockProvisioner != nil {
+				GetProvisioner = tc.mockProvisioner
+				t.Cleanup(awspca.ClearProvisioners)
+			}
+
+			result, signErr := controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name})
+			assert.Equal(t, tc.expectedSignResult, result, "Unexpected sign result")
+
+			result, getErr := controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name})
+			assert.Equal(t, tc.expectedGetResult, result, "Unexpected get result")
+
+			if tc.expectedTemplate != "" {
+				p, _ := GetProvisioner(ctx, fakeClient, tc.name, nil)
+				assert.Equal(t, tc.expectedTemplate, p.(*fakeProvisioner).pcaTemplateName)
+			}
+
+			if tc.expectedError && (signErr == nil && getErr == nil) {
+				assert.Fail(t, "Expected an error but got none")
+			}
+
+			var cr cmapi.CertificateRequest
+			err := fakeClient.Get(ctx, tc.name, &cr)
+			require.NoError(t, err, "unexpected error from fake client")
+			if err == nil {
+				if tc.expectedReadyConditionStatus != "" {
+					assertCertificateRequestHasReadyCondition(t, tc.expectedReadyConditionStatus, tc.expectedReadyConditionReason, &cr)
+				}
+				if tc.expectedCertificate != nil {
+					assert.Equal(t, tc.expectedCertificate, cr.Status.Certificate)
+				}
+				if tc.expectedCACertificate != nil {
+					assert.Equal(t, tc.expectedCACertificate, cr.Status.CA)
+				}
+				for key, value := range tc.expectedAnnotations {
+					assert.Equal(t, value, cr.GetAnnotations()[key], "unexpected annotation %s", key)
+				}
+			}
+
+			if tc.expectedEventReason != "" {
+				assertEventRecorded(t, recorder, tc.expectedEventReason)
+			}
+
+			if tc.expectedAuditDecisions != nil {
+				var decisions []audit.Decision
+				decoder := json.NewDecoder(&auditLog)
+				for decoder.More() {
+					var record audit.Record
+					require.NoError(t, decoder.Decode(&record))
+					assert.Equal(t, "cr1", record.CertificateRequest)
+					assert.Equal(t, "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012", record.CAArn)
+					decisions = append(decisions, record.Decision)
+				}
+				assert.Equal(t, tc.expectedAuditDecisions, decisions)
+			}
+		})
+	}
+}
+
+func assertCertificateRequestHasReadyCondition(t *testing.T, status cmmeta.ConditionStatus, reason string, cr *cmapi.CertificateRequest) {
+	condition := cmutil.GetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionReady)
+	if !assert.NotNil(t, condition, "Ready condition not found") {
+		return
+	}
+	assert.Equal(t, status, condition.Status, "unexpected condition status")
+	validReasons := sets.NewString(
+		cmapi.CertificateRequestReasonDenied,
+		cmapi.CertificateRequestReasonFailed,
+		cmapi.CertificateRequestReasonIssued,
+		cmapi.CertificateRequestReasonPending,
+	)
+	assert.Contains(t, validReasons, reason, "unexpected condition reason")
+	assert.Equal(t, reason, condition.Reason, "unexpected condition reason")
+}
+
+// TestCertificateRequestReconcile_RecordArn checks that the certificate ARN is
+// recorded with a patch that does not conflict with concurrent updates.
+func TestCertificateRequestReconcile_RecordArn(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	objects := []client.Object{
+		cmgen.CertificateRequest(
+			"cr1",
+			cmgen.SetCertificateRequestNamespace("ns1"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "issuer1",
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  "AWSPCAIssuer",
+			}),
+			cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+				Type:   cmapi.CertificateRequestConditionReady,
+				Status: cmmeta.ConditionUnknown,
+			}),
+		),
+		&issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "issuer1",
+				Namespace: "ns1",
+			},
+			Spec: issuerapi.AWSPCAIssuerSpec{
+				Region: "us-east-1",
+				Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+			},
+			Status: issuerapi.AWSPCAIssuerStatus{
+				Conditions: []metav1.Condition{
+					{Type: issuerapi.ConditionTypeReady, Status: metav1.ConditionTrue},
+				},
+			},
+		},
+	}
+
+	resource := cmapi.Resource("certificaterequests")
+
+	type testCase struct {
+		// patchErrs are returned, in order, by the patches recording the
+		// certificate ARN
+		patchErrs       []error
+		expectedPatches int
+		expectedError   bool
+	}
+
+	tests := map[string]testCase{
+		"success-despite-concurrent-update": {
+			expectedPatches: 1,
+		},
+		"success-after-transient-errors": {
+			patchErrs: []error{
+				apierrors.NewServerTimeout(resource, "patch", 1),
+				apierrors.NewTooManyRequests("slow down", 1),
+				apierrors.NewInternalError(errors.New("etcdserver: request timed out")),
+			},
+			expectedPatches: 4,
+		},
+		"failure-patch-rejected": {
+			patchErrs:       []error{apierrors.NewForbidden(resource, "cr1", errors.New("denied"))},
+			expectedPatches: 1,
+			expectedError:   true,
+		},
+		"failure-patch": {
+			patchErrs:       []error{errors.New("connection refused")},
+			expectedPatches: 1,
+			expectedError:   true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			patches := 0
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(objects...).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Update: func(ctx context.Context, client client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+						return apierrors.NewConflict(resource, "cr1", errors.New("conflict"))
+					},
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if _, ok := obj.GetAnnotations()[awspca.CertificateArnAnnotation]; !ok {
+							return c.Patch(ctx, obj, patch, opts...)
+						}
+						patches++
+						if patches <= len(tc.patchErrs) {
+							return tc.patchErrs[patches-1]
+						}
+						// Another writer modifies the CertificateRequest first
+						concurrent := new(cmapi.CertificateRequest)
+						require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(obj), concurrent))
+						metav1.SetMetaDataLabel(&concurrent.ObjectMeta, "concurrent", "true")
+						require.NoError(t, c.Update(ctx, concurrent))
+						return c.Patch(ctx, obj, patch, opts...)
+					},
+				}).
+				Build()
+
+			controller := CertificateRequestReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+				Clock:    clocktesting.NewFakeClock(time.Now()),
+			}
+
+			GetProvisioner = generateMockGetProvisioner(&fakeProvisioner{cert: []byte("cert"), caCert: []byte("cacert")}, nil)
+			t.Cleanup(awspca.ClearProvisioners)
+
+			name := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+			result, err := controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: name})
+			assert.Equal(t, tc.expectedPatches, patches, "unexpected number of patches")
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, result.Requeue, "signing should requeue to get the certificate")
+
+			var cr cmapi.CertificateRequest
+			require.NoError(t, fakeClient.Get(context.TODO(), name, &cr))
+			assert.Equal(t, "arn", cr.GetAnnotations()[awspca.CertificateArnAnnotation])
+			assert.Equal(t, "true", cr.GetLabels()["concurrent"])
+		})
+	}
+}
+
+func assertEventRecorded(t *testing.T, recorder *record.FakeRecorder, reason string) {
+	t.Helper()
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.Contains(event, " "+reason+" ") {
+				return
+			}
+		default:
+			assert.Fail(t, "event not recorded", "reason %s", reason)
+			return
+		}
+	}
+}
+
+// TestCertificateRequestReconcile_RecordIssuanceStart checks that the
+// idempotency token is recorded before signing, and that signing again after
+// PCA's idempotency window is reported.
+func TestCertificateRequestReconcile_RecordIssuanceStart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	type testCase struct {
+		started       time.Time
+		expectedEvent bool
+	}
+
+	tests := map[string]testCase{
+		"first-submission": {},
+		"within-idempotency-window": {
+			started: now.Add(-time.Minute),
+		},
+		"after-idempotency-window": {
+			started:       now.Add(-10 * time.Minute),
+			expectedEvent: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cr := cmgen.CertificateRequest(
+				"cr1",
+				cmgen.SetCertificateRequestNamespace("ns1"),
+				cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+					Name:  "issuer1",
+					Group: issuerapi.GroupVersion.Group,
+					Kind:  "AWSPCAIssuer",
+				}),
+				cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+					Type:   cmapi.CertificateRequestConditionReady,
+					Status: cmmeta.ConditionUnknown,
+				}),
+			)
+			if !tc.started.IsZero() {
+				metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.IdempotencyTokenAnnotation, awspca.IdempotencyToken(cr))
+				metav1.SetMetaDataAnnotation(&cr.ObjectMeta, awspca.IssuanceStartedAnnotation, tc.started.Format(time.RFC3339))
+			}
+			objects := []client.Object{
+				cr,
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{Type: issuerapi.ConditionTypeReady, Status: metav1.ConditionTrue},
+						},
+					},
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(objects...).
+				Build()
+			recorder := record.NewFakeRecorder(10)
+			controller := CertificateRequestReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: recorder,
+				Clock:    clocktesting.NewFakeClock(now),
+			}
+
+			// Signing fails, so only the annotations recorded beforehand remain
+			GetProvisioner = generateMockGetProvisioner(&fakeProvisioner{signErr: errors.New("sign failed")}, nil)
+			t.Cleanup(awspca.ClearProvisioners)
 
-	result, err := controller.Reconcile(context.TODO(), reconcile.Request{
-		NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "cr1"},
-	})
+			name := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+			_, err := controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: name})
+			require.NoError(t, err)
+
+			var got cmapi.CertificateRequest
+			require.NoError(t, fakeClient.Get(context.TODO(), name, &got))
+			started := tc.started
+			if started.IsZero() {
+				started = now
+			}
+			assert.NotEmpty(t, got.GetAnnotations()[awspca.IdempotencyTokenAnnotation])
+			assert.Equal(t, started.Format(time.RFC3339), got.GetAnnotations()[awspca.IssuanceStartedAnnotation])
 
-	assert.NoError(t, err, "conflict should not return an error")
-	assert.True(t, result.Requeue, "conflict should trigger requeue")
+			if tc.expectedEvent {
+				assertEventRecorded(t, recorder, "DuplicateIssuance")
+				return
+			}
+			for len(recorder.Events) > 0 {
+				assert.NotContains(t, <-recorder.Events, "DuplicateIssuance")
+			}
+		})
+	}
 }
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/audit"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const dryRunMessage = "dry run: certificate not requested from PCA"
+
+// isDryRun returns whether a CertificateRequest is only evaluated, because
+// either its issuer or the request itself asks for a dry run
+func isDryRun(iss api.GenericIssuer, cr *cmapi.CertificateRequest) bool {
+	return iss.GetSpec().DryRun || cr.GetAnnotations()[awspca.DryRunAnnotation] == "true"
+}
+
+// dryRun records on the CertificateRequest the IssueCertificate input that
+// would have been sent to PCA, and marks the request as failed since no
+// certificate is issued for it
+func (r *CertificateRequestReconciler) dryRun(ctx context.Context, cr *cmapi.CertificateRequest, iss api.GenericIssuer, provisioner awspca.GenericProvisioner, pcaTemplateName string, log logr.Logger) error {
+	base := cr.DeepCopy()
+	if err := provisioner.DryRun(ctx, cr, pcaTemplateName, log); err != nil {
+		log.Error(err, "failed to evaluate certificate request in dry run")
+		message := "dry run failed: " + err.Error()
+		r.audit(cr, iss, audit.DecisionFailed, message)
+		return r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, message)
+	}
+
+	if err := r.Client.Patch(ctx, cr, client.MergeFrom(base)); err != nil {
+		log.Error(err, "failed to record dry run input on CertificateRequest")
+		return err
+	}
+	r.Recorder.Event(cr, core.EventTypeNormal, "DryRun",
+		"IssueCertificate input: "+cr.GetAnnotations()[awspca.DryRunInputAnnotation])
+
+	if cr.Status.FailureTime == nil {
+		nowTime := metav1.NewTime(r.Clock.Now())
+		cr.Status.FailureTime = &nowTime
+	}
+	r.audit(cr, iss, audit.DecisionDryRun, dryRunMessage)
+	return r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, dryRunMessage)
+}
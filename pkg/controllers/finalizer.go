@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// issuerFinalizer lets the issuer controllers clean up after a deleted issuer
+const issuerFinalizer = "awspca.cert-manager.io/finalizer"
+
+// deletionBlockedInterval is how often the deletion of an issuer blocked by
+// CertificateRequests in flight is retried
+const deletionBlockedInterval = 30 * time.Second
+
+// addFinalizer adds issuerFinalizer to an issuer that does not have it yet
+func (r *GenericIssuerReconciler) addFinalizer(ctx context.Context, issuer api.GenericIssuer) error {
+	if !controllerutil.AddFinalizer(issuer, issuerFinalizer) {
+		return nil
+	}
+	return r.Client.Update(ctx, issuer)
+}
+
+// finalize cleans up after a deleted issuer: it evicts the provisioner with
+// the credentials of the issuer, deletes or retains its trust bundles, and
+// then removes issuerFinalizer.
+func (r *GenericIssuerReconciler) finalize(ctx context.Context, req ctrl.Request, issuer api.GenericIssuer) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(issuer, issuerFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	log := r.Log.WithValues("genericissuer", req.NamespacedName)
+	spec := issuer.GetSpec()
+
+	inFlight, err := r.inFlightRequests(ctx, issuer)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if inFlight > 0 {
+		if spec.DeletionPolicy == api.DeletionPolicyBlock {
+			log.Info("deletion blocked by CertificateRequests in flight", "count", inFlight)
+			r.Recorder.Eventf(issuer, core.EventTypeWarning, "DeletionBlocked",
+				"Waiting for %d CertificateRequests in flight before deleting the issuer", inFlight)
+			return ctrl.Result{RequeueAfter: deletionBlockedInterval}, nil
+		}
+		r.Recorder.Eventf(issuer, core.EventTypeWarning, "InFlightCertificateRequests",
+			"Deleting the issuer while %d CertificateRequests are in flight, they will not be signed", inFlight)
+	}
+
+	if spec.TrustBundle != nil {
+		if err := r.cleanUpTrustBundles(ctx, issuer); err != nil {
+			log.Error(err, "failed to clean up trust bundles")
+			r.Recorder.Event(issuer, core.EventTypeWarning, "TrustBundle", err.Error())
+			return ctrl.Result{}, err
+		}
+	}
+
+	awspca.DeleteProvisioner(ctx, r.Client, req.NamespacedName)
+	r.forgetIssuer(issuerKind(issuer), req.NamespacedName)
+	r.Recorder.Event(issuer, core.EventTypeNormal, "Finalized", "Cleaned up after the issuer")
+
+	controllerutil.RemoveFinalizer(issuer, issuerFinalizer)
+	return ctrl.Result{}, r.Client.Update(ctx, issuer)
+}
+
+// inFlightRequests counts the CertificateRequests of an issuer that are not
+// Ready, Failed or Denied yet
+func (r *GenericIssuerReconciler) inFlightRequests(ctx context.Context, issuer api.GenericIssuer) (int, error) {
+	crList := new(cmapi.CertificateRequestList)
+	if err := r.Client.List(ctx, crList, client.MatchingFields{
+		issuerRefIndex: issuerRefKey(issuerKind(issuer), issuer.GetNamespace(), issuer.GetName()),
+	}); err != nil {
+		return 0, fmt.Errorf("failed to list CertificateRequests: %w", err)
+	}
+
+	inFlight := 0
+	for i := range crList.Items {
+		if !isFinished(&crList.Items[i]) {
+			inFlight++
+		}
+	}
+	return inFlight, nil
+}
+
+// cleanUpTrustBundles deletes the trust bundles published by an issuer, or
+// releases them from the issuer if they are retained, so that the garbage
+// collector does not delete them.
+func (r *GenericIssuerReconciler) cleanUpTrustBundles(ctx context.Context, issuer api.GenericIssuer) error {
+	trustBundle := issuer.GetSpec().TrustBundle
+	namespaces, err := r.trustBundleNamespaces(ctx, issuer)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		var obj client.Object = &core.ConfigMap{}
+		if trustBundle.Kind == api.TrustBundleKindSecret {
+			obj = &core.Secret{}
+		}
+		key := client.ObjectKey{Namespace: namespace, Name: trustBundle.Name}
+		if err := r.Client.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get trust bundle %s: %w", key, err)
+		}
+		if !metav1.IsControlledBy(obj, issuer) {
+			continue
+		}
+
+		if trustBundle.Retain {
+			if err := controllerutil.RemoveControllerReference(issuer, obj, r.Scheme); err != nil {
+				return err
+			}
+			if err := r.Client.Update(ctx, obj); err != nil {
+				return fmt.Errorf("failed to retain trust bundle %s: %w", key, err)
+			}
+			r.Recorder.Eventf(issuer, core.EventTypeNormal, "TrustBundleRetained", "Trust bundle %s retained", key)
+			continue
+		}
+		if err := r.Client.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete trust bundle %s: %w", key, err)
+		}
+		r.Recorder.Eventf(issuer, core.EventTypeNormal, "TrustBundleDeleted", "Trust bundle %s deleted", key)
+	}
+	return nil
+}
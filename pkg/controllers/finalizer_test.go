@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestIssuerFinalizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	issuer := func(policy issuerapi.DeletionPolicy, retain bool) *issuerapi.AWSPCAIssuer {
+		return &issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name.Name,
+				Namespace:         name.Namespace,
+				UID:               "issuer1-uid",
+				Finalizers:        []string{issuerFinalizer},
+				DeletionTimestamp: &metav1.Time{Time: metav1.Now().Time},
+			},
+			Spec: issuerapi.AWSPCAIssuerSpec{
+				Region:         "us-east-1",
+				Arn:            "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				DeletionPolicy: policy,
+				TrustBundle: &issuerapi.TrustBundle{
+					Name:   "ca-bundle",
+					Retain: retain,
+				},
+			},
+		}
+	}
+	inFlightRequest := cmgen.CertificateRequest("cr1",
+		cmgen.SetCertificateRequestNamespace(name.Namespace),
+		cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+			Name:  name.Name,
+			Group: issuerapi.GroupVersion.Group,
+			Kind:  "AWSPCAIssuer",
+		}))
+
+	type testCase struct {
+		issuer          *issuerapi.AWSPCAIssuer
+		objects         []client.Object
+		expectedResult  ctrl.Result
+		expectedDeleted bool
+		expectedBundle  bool
+		expectedOwned   bool
+		expectedEvent   string
+	}
+
+	tests := map[string]testCase{
+		"success-delete-trust-bundle": {
+			issuer:          issuer(issuerapi.DeletionPolicyWarn, false),
+			expectedDeleted: true,
+			expectedEvent:   "TrustBundleDeleted",
+		},
+		"success-retain-trust-bundle": {
+			issuer:          issuer(issuerapi.DeletionPolicyWarn, true),
+			expectedDeleted: true,
+			expectedBundle:  true,
+			expectedEvent:   "TrustBundleRetained",
+		},
+		"success-warn-in-flight": {
+			issuer:          issuer(issuerapi.DeletionPolicyWarn, false),
+			objects:         []client.Object{inFlightRequest},
+			expectedDeleted: true,
+			expectedEvent:   "InFlightCertificateRequests",
+		},
+		"blocked-in-flight": {
+			issuer:         issuer(issuerapi.DeletionPolicyBlock, false),
+			objects:        []client.Object{inFlightRequest},
+			expectedResult: ctrl.Result{RequeueAfter: deletionBlockedInterval},
+			expectedBundle: true,
+			expectedOwned:  true,
+			expectedEvent:  "DeletionBlocked",
+		},
+	}
+
+	for tn, tc := range tests {
+		t.Run(tn, func(t *testing.T) {
+			bundle := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: name.Namespace},
+				Data:       map[string]string{defaultTrustBundleKey: "ca"},
+			}
+			require.NoError(t, controllerutil.SetControllerReference(tc.issuer, bundle, scheme))
+
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(tc.objects, tc.issuer, bundle)...).
+				WithIndex(&cmapi.CertificateRequest{}, issuerRefIndex, indexIssuerRef).
+				Build()
+			fakeRecorder := record.NewFakeRecorder(10)
+
+			controller := AWSPCAIssuerReconciler{
+				Client: fakeClient,
+				Log:    logrtesting.NewTestLogger(t),
+				Scheme: scheme,
+				GenericController: &GenericIssuerReconciler{
+					Client:   fakeClient,
+					Log:      logrtesting.NewTestLogger(t),
+					Scheme:   scheme,
+					Recorder: fakeRecorder,
+				},
+			}
+
+			result, err := controller.Reconcile(context.TODO(), ctrl.Request{NamespacedName: name})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResult, result)
+
+			err = fakeClient.Get(context.TODO(), name, new(issuerapi.AWSPCAIssuer))
+			assert.Equal(t, tc.expectedDeleted, apierrors.IsNotFound(err), "issuer deleted")
+
+			gotBundle := new(v1.ConfigMap)
+			err = fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(bundle), gotBundle)
+			if assert.Equal(t, tc.expectedBundle, err == nil, "trust bundle exists") && err == nil {
+				assert.Equal(t, tc.expectedOwned, metav1.IsControlledBy(gotBundle, tc.issuer), "trust bundle controlled by the issuer")
+			}
+
+			var events []string
+			for len(fakeRecorder.Events) > 0 {
+				events = append(events, <-fakeRecorder.Events)
+			}
+			assert.Contains(t, strings.Join(events, "\n"), tc.expectedEvent)
+		})
+	}
+}
+
+func TestIssuerReconcileAddsFinalizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+
+	name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	issuer := &issuerapi.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer).
+		WithStatusSubresource(issuer).
+		Build()
+	controller := GenericIssuerReconciler{
+		Client:   fakeClient,
+		Log:      logrtesting.NewTestLogger(t),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// The issuer fails validation, but it is protected all the same
+	_, _ = controller.Reconcile(context.TODO(), ctrl.Request{NamespacedName: name}, issuer)
+
+	got := new(issuerapi.AWSPCAIssuer)
+	require.NoError(t, fakeClient.Get(context.TODO(), name, got))
+	assert.Contains(t, got.Finalizers, issuerFinalizer)
+}
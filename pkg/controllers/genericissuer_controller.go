@@ -20,16 +20,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
 	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/health"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/tracing"
 	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -40,8 +44,6 @@ var (
 	errNoRegionInSpec = errors.New("no Region found in Issuer Spec")
 )
 
-var awsDefaultRegion = os.Getenv("AWS_REGION")
-
 // GenericIssuerReconciler reconciles both AWSPCAIssuer and AWSPCAClusterIssuer objects
 type GenericIssuerReconciler struct {
 	client.Client
@@ -55,6 +57,22 @@ type GenericIssuerReconciler struct {
 	// but can be skipped during unit tests to avoid having a dependency on a
 	// live STS service.
 	GetCallerIdentity bool
+
+	// TrackCACertificate should be set to true if you want to fetch the CA
+	// certificate from PCA and track its fingerprint in the issuer status.
+	// It is always fetched when the issuer publishes a trust bundle.
+	TrackCACertificate bool
+
+	// RequireRevocation should be set to true if every issuer must have a CA
+	// with a CRL or OCSP enabled, regardless of its revocationCheck.
+	RequireRevocation bool
+
+	// IssuerFilter selects the issuers reconciled by this instance
+	IssuerFilter IssuerFilter
+
+	// Health, if set, records the outcome of every reconcile for the
+	// readiness check
+	Health *health.Checker
 }
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -62,17 +80,43 @@ type GenericIssuerReconciler struct {
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
-func (r *GenericIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Request, issuer api.GenericIssuer) (ctrl.Result, error) {
+func (r *GenericIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Request, issuer api.GenericIssuer) (result ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "Issuer.Reconcile",
+		attribute.String("issuer.namespace", req.Namespace),
+		attribute.String("issuer.name", req.Name),
+	)
+	defer func() { tracing.End(span, err) }()
+	if r.Health != nil {
+		defer func() { r.Health.RecordIssuer(issuerKind(issuer), req.NamespacedName, err) }()
+	}
+
 	log := r.Log.WithValues("genericissuer", req.NamespacedName)
+	if err := r.addFinalizer(ctx, issuer); err != nil {
+		log.Error(err, "failed to add finalizer")
+		return ctrl.Result{}, err
+	}
+
 	spec := issuer.GetSpec()
-	err := validateIssuer(spec)
+	err = validateIssuer(spec)
+	if err == nil {
+		err = validateTrustBundle(issuer)
+	}
 	if err != nil {
 		log.Error(err, "failed to validate issuer")
 		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, "Validation", fmt.Sprintf("Failed to validate resource: %v", err))
 		return ctrl.Result{}, err
 	}
 
-	awspca.DeleteProvisioner(ctx, r.Client, req.NamespacedName)
+	// The provisioner is only recreated when the issuer or its credentials
+	// changed, so that its poller keeps the certificates it is waiting for
+	version, err := awspca.ConfigVersion(ctx, r.Client, issuer.GetGeneration(), spec)
+	if err != nil {
+		log.Error(err, "Error loading config")
+		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, "Error", err.Error())
+		return ctrl.Result{}, err
+	}
+	awspca.RefreshProvisioner(req.NamespacedName, version)
+
 	cfg, err := awspca.GetConfig(ctx, r.Client, spec)
 	if err != nil {
 		log.Error(err, "Error loading config")
@@ -89,7 +133,70 @@ func (r *GenericIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		log.Info("sts.GetCallerIdentity", "arn", id.Arn, "account", id.Account, "user_id", id.UserId)
 	}
 
-	return ctrl.Result{}, r.setStatus(ctx, issuer, metav1.ConditionTrue, "Verified", "Issuer verified")
+	if err := r.checkRevocation(ctx, req, issuer); err != nil {
+		log.Error(err, "failed to check revocation configuration")
+		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, "RevocationCheck", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if err := r.setStatus(ctx, issuer, metav1.ConditionTrue, "Verified", "Issuer verified"); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !r.TrackCACertificate && spec.TrustBundle == nil {
+		// The revocation configuration of the CA can change at any time
+		if r.revocationCheck(spec) != api.RevocationCheckNone {
+			return ctrl.Result{RequeueAfter: caRefreshInterval(spec)}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+
+	bundle, err := r.getCACertificate(ctx, req, spec)
+	if err != nil {
+		log.Error(err, "failed to get CA certificate")
+		r.Recorder.Event(issuer, core.EventTypeWarning, "CACertificate", err.Error())
+		// The CA certificate is only essential when it is published
+		if spec.TrustBundle != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: caRefreshInterval(spec)}, nil
+	}
+
+	if err := r.trackCACertificate(ctx, issuer, bundle); err != nil {
+		log.Error(err, "failed to track CA certificate")
+		return ctrl.Result{}, err
+	}
+
+	if spec.TrustBundle != nil {
+		if err := r.publishTrustBundle(ctx, issuer, bundle); err != nil {
+			log.Error(err, "failed to publish trust bundle")
+			r.Recorder.Event(issuer, core.EventTypeWarning, "TrustBundle", err.Error())
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: caRefreshInterval(spec)}, nil
+}
+
+func (r *GenericIssuerReconciler) getCACertificate(ctx context.Context, req ctrl.Request, spec *api.AWSPCAIssuerSpec) ([]byte, error) {
+	provisioner, err := GetProvisioner(ctx, r.Client, req.NamespacedName, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := provisioner.GetCACertificate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CA certificate: %w", err)
+	}
+	return bundle, nil
+}
+
+// caRefreshInterval returns how often the CA certificate is fetched from PCA
+func caRefreshInterval(spec *api.AWSPCAIssuerSpec) time.Duration {
+	if spec.TrustBundle != nil && spec.TrustBundle.RefreshInterval != nil {
+		return spec.TrustBundle.RefreshInterval.Duration
+	}
+	return defaultCARefreshInterval
 }
 
 func (r *GenericIssuerReconciler) setStatus(ctx context.Context, issuer api.GenericIssuer, status metav1.ConditionStatus, reason, message string) error {
@@ -105,12 +212,25 @@ func (r *GenericIssuerReconciler) setStatus(ctx context.Context, issuer api.Gene
 	return r.Client.Status().Update(ctx, issuer)
 }
 
+// forgetIssuer stops tracking the health of a deleted issuer
+func (r *GenericIssuerReconciler) forgetIssuer(kind string, name types.NamespacedName) {
+	if r.Health != nil {
+		r.Health.ForgetIssuer(kind, name)
+	}
+}
+
 func validateIssuer(spec *api.AWSPCAIssuerSpec) error {
 	switch {
 	case spec.Arn == "":
 		return errNoArnInSpec
-	case spec.Region == "" && awsDefaultRegion == "":
+	case spec.Region == "" && awspca.GetDefaults().Region == "":
 		return errNoRegionInSpec
 	}
-	return nil
+	if spec.ApprovalPolicy != nil {
+		if err := validateApprovalPolicy(spec.ApprovalPolicy); err != nil {
+			return err
+		}
+	}
+	_, err := awspca.ParseClientRequestID(spec.ClientRequestID)
+	return err
 }
@@ -16,18 +16,28 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/health"
+	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
 	logrtesting "github.com/go-logr/logr/testing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -41,10 +51,6 @@ const (
 )
 
 func TestIssuerReconcile(t *testing.T) {
-	origAWSDefaultRegion := awsDefaultRegion
-	awsDefaultRegion = ""
-	t.Cleanup(func() { awsDefaultRegion = origAWSDefaultRegion })
-
 	type testCase struct {
 		kind                         string
 		name                         types.NamespacedName
@@ -264,6 +270,92 @@ func TestIssuerReconcile(t *testing.T) {
 			expectedError:                errNoArnInSpec,
 			expectedResult:               ctrl.Result{},
 		},
+		"failure-issuer-invalid-client-request-id": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region:          "us-east-1",
+						Arn:             "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						ClientRequestID: "{{ .Tenant }}",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                awspca.ErrInvalidClientRequestID,
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-invalid-approval-policy": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region:         "us-east-1",
+						Arn:            "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						ApprovalPolicy: &issuerapi.ApprovalPolicy{AllowedIPRanges: []string{"10.0.0.0"}},
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                errInvalidApprovalPolicy,
+			expectedResult:               ctrl.Result{},
+		},
 		"failure-issuer-no-access-key-specified": {
 			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
 			objects: []client.Object{
@@ -450,11 +542,13 @@ func TestIssuerReconcile(t *testing.T) {
 				WithStatusSubresource(tc.objects...).
 				Build()
 
+			checker := &health.Checker{}
 			controller := GenericIssuerReconciler{
 				Client:   fakeClient,
 				Log:      logrtesting.NewTestLogger(t),
 				Scheme:   scheme,
 				Recorder: record.NewFakeRecorder(10),
+				Health:   checker,
 			}
 
 			var (
@@ -494,6 +588,12 @@ func TestIssuerReconcile(t *testing.T) {
 			if tc.expectedReadyConditionStatus != "" {
 				assertIssuerHasReadyCondition(t, tc.expectedReadyConditionStatus, &status)
 			}
+
+			issuers := checker.Issuers()
+			if assert.Len(t, issuers, 1) {
+				assert.Equal(t, tc.name.Name, issuers[0].Name)
+				assert.Equal(t, err == nil, issuers[0].Healthy)
+			}
 		})
 	}
 }
@@ -502,10 +602,509 @@ func assertErrorIs(t *testing.T, expectedError, actualError error) {
 	if !assert.Error(t, actualError) {
 		return
 	}
-	assert.Equal(t, actualError, expectedError, "Errors do not match!")
+	assert.ErrorIs(t, actualError, expectedError, "Errors do not match!")
 }
 
 func assertIssuerHasReadyCondition(t *testing.T, status metav1.ConditionStatus, issuerStatus *issuerapi.AWSPCAIssuerStatus) {
 	fmt.Printf("%v", issuerStatus.Conditions)
 	assert.Equal(t, status, issuerStatus.Conditions[0].Status, "unexpected condition status")
 }
+
+func TestIssuerReconcile_TrustBundle(t *testing.T) {
+	caBundle := []byte("-----BEGIN CERTIFICATE-----\nY2E=\n-----END CERTIFICATE-----\n")
+	spec := func(trustBundle *issuerapi.TrustBundle) issuerapi.AWSPCAIssuerSpec {
+		return issuerapi.AWSPCAIssuerSpec{
+			Region:      "us-east-1",
+			Arn:         "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+			TrustBundle: trustBundle,
+		}
+	}
+	namespace := func(name string, labels map[string]string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"trust": "pca"}}
+
+	type testCase struct {
+		kind                 string
+		name                 types.NamespacedName
+		objects              []client.Object
+		provisioner          *fakeProvisioner
+		expectedResult       ctrl.Result
+		expectedError        error
+		expectedReadyStatus  metav1.ConditionStatus
+		expectedConfigMaps   []types.NamespacedName
+		expectedSecrets      []types.NamespacedName
+		unexpectedConfigMaps []types.NamespacedName
+		foreignConfigMaps    []types.NamespacedName
+		expectedKey          string
+		expectedPublished    metav1.ConditionStatus
+	}
+
+	tests := map[string]testCase{
+		"success-issuer-configmap": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+					Spec:       spec(&issuerapi.TrustBundle{Name: "pca-ca"}),
+				},
+			},
+			provisioner:         &fakeProvisioner{caBundle: caBundle},
+			expectedResult:      ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+			expectedReadyStatus: metav1.ConditionTrue,
+			expectedConfigMaps:  []types.NamespacedName{{Namespace: "ns1", Name: "pca-ca"}},
+			expectedKey:         defaultTrustBundleKey,
+			expectedPublished:   metav1.ConditionTrue,
+		},
+		"success-issuer-secret": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+					Spec: spec(&issuerapi.TrustBundle{
+						Kind:            issuerapi.TrustBundleKindSecret,
+						Name:            "pca-ca",
+						Key:             "bundle.pem",
+						RefreshInterval: &metav1.Duration{Duration: 10 * time.Minute},
+					}),
+				},
+			},
+			provisioner:         &fakeProvisioner{caBundle: caBundle},
+			expectedResult:      ctrl.Result{RequeueAfter: 10 * time.Minute},
+			expectedReadyStatus: metav1.ConditionTrue,
+			expectedSecrets:     []types.NamespacedName{{Namespace: "ns1", Name: "pca-ca"}},
+			expectedKey:         "bundle.pem",
+			expectedPublished:   metav1.ConditionTrue,
+		},
+		"success-cluster-issuer-namespace-selector": {
+			kind: ClusterIssuer,
+			name: types.NamespacedName{Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1"},
+					Spec:       spec(&issuerapi.TrustBundle{Name: "pca-ca", NamespaceSelector: selector}),
+				},
+				namespace("ns1", map[string]string{"trust": "pca"}),
+				namespace("ns2", map[string]string{"trust": "pca"}),
+				namespace("ns3", nil),
+			},
+			provisioner:          &fakeProvisioner{caBundle: caBundle},
+			expectedResult:       ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+			expectedReadyStatus:  metav1.ConditionTrue,
+			expectedConfigMaps:   []types.NamespacedName{{Namespace: "ns1", Name: "pca-ca"}, {Namespace: "ns2", Name: "pca-ca"}},
+			unexpectedConfigMaps: []types.NamespacedName{{Namespace: "ns3", Name: "pca-ca"}},
+			expectedKey:          defaultTrustBundleKey,
+			expectedPublished:    metav1.ConditionTrue,
+		},
+		"failure-cluster-issuer-configmap-not-controlled": {
+			kind: ClusterIssuer,
+			name: types.NamespacedName{Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1"},
+					Spec:       spec(&issuerapi.TrustBundle{Name: "pca-ca", NamespaceSelector: selector}),
+				},
+				namespace("ns1", map[string]string{"trust": "pca"}),
+				namespace("ns2", map[string]string{"trust": "pca"}),
+				&v1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "pca-ca"},
+					Data:       map[string]string{defaultTrustBundleKey: "other"},
+				},
+			},
+			provisioner:         &fakeProvisioner{caBundle: caBundle},
+			expectedError:       errTrustBundleNotControlled,
+			expectedReadyStatus: metav1.ConditionTrue,
+			expectedConfigMaps:  []types.NamespacedName{{Namespace: "ns2", Name: "pca-ca"}},
+			foreignConfigMaps:   []types.NamespacedName{{Namespace: "ns1", Name: "pca-ca"}},
+			expectedKey:         defaultTrustBundleKey,
+			expectedPublished:   metav1.ConditionFalse,
+		},
+		"failure-cluster-issuer-no-namespace-selector": {
+			kind: ClusterIssuer,
+			name: types.NamespacedName{Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1"},
+					Spec:       spec(&issuerapi.TrustBundle{Name: "pca-ca"}),
+				},
+			},
+			provisioner:         &fakeProvisioner{caBundle: caBundle},
+			expectedError:       errNoTrustBundleNamespaceSelector,
+			expectedReadyStatus: metav1.ConditionFalse,
+		},
+		"failure-issuer-namespace-selector": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+					Spec:       spec(&issuerapi.TrustBundle{Name: "pca-ca", NamespaceSelector: selector}),
+				},
+			},
+			provisioner:         &fakeProvisioner{caBundle: caBundle},
+			expectedError:       errTrustBundleNamespaceSelector,
+			expectedReadyStatus: metav1.ConditionFalse,
+		},
+		"failure-get-ca-certificate": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+					Spec:       spec(&issuerapi.TrustBundle{Name: "pca-ca"}),
+				},
+			},
+			provisioner:          &fakeProvisioner{caBundleErr: errors.New("access denied")},
+			expectedError:        errors.New("access denied"),
+			expectedReadyStatus:  metav1.ConditionTrue,
+			unexpectedConfigMaps: []types.NamespacedName{{Namespace: "ns1", Name: "pca-ca"}},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(tc.objects...).
+				WithStatusSubresource(tc.objects...).
+				Build()
+
+			controller := GenericIssuerReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			GetProvisioner = generateMockGetProvisioner(tc.provisioner, nil)
+			t.Cleanup(func() { GetProvisioner = awspca.GetProvisioner })
+
+			ctx := context.TODO()
+
+			var iss issuerapi.GenericIssuer
+			if tc.kind == ClusterIssuer {
+				iss = new(issuerapi.AWSPCAClusterIssuer)
+			} else {
+				iss = new(issuerapi.AWSPCAIssuer)
+			}
+			require.NoError(t, fakeClient.Get(ctx, tc.name, iss))
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name}, iss)
+
+			if tc.expectedError != nil {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.expectedError.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedResult, result, "Unexpected result")
+			assertIssuerHasReadyCondition(t, tc.expectedReadyStatus, iss.GetStatus())
+
+			for _, name := range tc.expectedConfigMaps {
+				configMap := new(v1.ConfigMap)
+				require.NoError(t, fakeClient.Get(ctx, name, configMap))
+				assert.Equal(t, string(caBundle), configMap.Data[tc.expectedKey])
+				assert.True(t, metav1.IsControlledBy(configMap, iss), "trust bundle is not controlled by the issuer")
+			}
+			for _, name := range tc.expectedSecrets {
+				secret := new(v1.Secret)
+				require.NoError(t, fakeClient.Get(ctx, name, secret))
+				assert.Equal(t, caBundle, secret.Data[tc.expectedKey])
+				assert.True(t, metav1.IsControlledBy(secret, iss), "trust bundle is not controlled by the issuer")
+			}
+			for _, name := range tc.unexpectedConfigMaps {
+				err := fakeClient.Get(ctx, name, new(v1.ConfigMap))
+				assert.True(t, apierrors.IsNotFound(err), "unexpected trust bundle %s", name)
+			}
+			for _, name := range tc.foreignConfigMaps {
+				configMap := new(v1.ConfigMap)
+				require.NoError(t, fakeClient.Get(ctx, name, configMap))
+				assert.Equal(t, "other", configMap.Data[defaultTrustBundleKey])
+				assert.Empty(t, configMap.OwnerReferences, "trust bundle adopted by the issuer")
+			}
+			if tc.expectedPublished != "" {
+				condition := meta.FindStatusCondition(iss.GetStatus().Conditions, issuerapi.ConditionTypeTrustBundlePublished)
+				if assert.NotNil(t, condition) {
+					assert.Equal(t, tc.expectedPublished, condition.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestIssuerReconcile_CARotation(t *testing.T) {
+	caBundle := []byte("-----BEGIN CERTIFICATE-----\nY2E=\n-----END CERTIFICATE-----\n")
+	fingerprint, err := caCertificateFingerprint(caBundle)
+	require.NoError(t, err)
+	oldFingerprint := "0123456789abcdef"
+
+	issuer := func(fingerprint string, reissue bool) *issuerapi.AWSPCAIssuer {
+		return &issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+			Spec: issuerapi.AWSPCAIssuerSpec{
+				Region:              "us-east-1",
+				Arn:                 "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				ReissueOnCARotation: reissue,
+			},
+			Status: issuerapi.AWSPCAIssuerStatus{CACertificateFingerprint: fingerprint},
+		}
+	}
+	certificateRequest := func(name, issuerName, certificate, revision, fingerprint string) *cmapi.CertificateRequest {
+		return cmgen.CertificateRequest(name,
+			cmgen.SetCertificateRequestNamespace("ns1"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  issuerName,
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  "AWSPCAIssuer",
+			}),
+			cmgen.AddCertificateRequestAnnotations(map[string]string{
+				cmapi.CertificateNameKey:                      certificate,
+				cmapi.CertificateRequestRevisionAnnotationKey: revision,
+				awspca.CACertificateFingerprintAnnotation:     fingerprint,
+			}),
+		)
+	}
+	certificate := &cmapi.Certificate{
+		ObjectMeta: metav1.ObjectMeta{Name: "crt1", Namespace: "ns1"},
+		Status:     cmapi.CertificateStatus{Revision: ptr.To(2)},
+	}
+
+	type testCase struct {
+		objects                   []client.Object
+		provisioner               *fakeProvisioner
+		expectedResult            ctrl.Result
+		expectedRotatedCondition  bool
+		expectedRotatedRequests   []string
+		unexpectedRotatedRequests []string
+		expectedReissue           bool
+	}
+
+	tests := map[string]testCase{
+		"success-first-observation": {
+			objects:        []client.Object{issuer("", true)},
+			provisioner:    &fakeProvisioner{caBundle: caBundle},
+			expectedResult: ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+		},
+		"success-unchanged": {
+			objects: []client.Object{
+				issuer(fingerprint, true),
+				certificateRequest("cr1", "issuer1", "crt1", "2", oldFingerprint),
+				certificate.DeepCopy(),
+			},
+			provisioner:               &fakeProvisioner{caBundle: caBundle},
+			expectedResult:            ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+			unexpectedRotatedRequests: []string{"cr1"},
+		},
+		"success-rotated": {
+			objects: []client.Object{
+				issuer(oldFingerprint, false),
+				certificateRequest("cr1", "issuer1", "crt1", "2", oldFingerprint),
+				certificate.DeepCopy(),
+			},
+			provisioner:               &fakeProvisioner{caBundle: caBundle},
+			expectedResult:            ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+			expectedRotatedCondition:  true,
+			unexpectedRotatedRequests: []string{"cr1"},
+		},
+		"success-rotated-reissue": {
+			objects: []client.Object{
+				issuer(oldFingerprint, true),
+				certificateRequest("cr1", "issuer1", "crt1", "1", oldFingerprint),
+				certificateRequest("cr2", "issuer1", "crt1", "2", oldFingerprint),
+				certificateRequest("cr3", "issuer1", "crt2", "1", fingerprint),
+				certificateRequest("cr4", "issuer2", "crt3", "1", oldFingerprint),
+				certificate.DeepCopy(),
+			},
+			provisioner:               &fakeProvisioner{caBundle: caBundle},
+			expectedResult:            ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+			expectedRotatedCondition:  true,
+			expectedRotatedRequests:   []string{"cr1", "cr2"},
+			unexpectedRotatedRequests: []string{"cr3", "cr4"},
+			expectedReissue:           true,
+		},
+		"success-get-ca-certificate-failure": {
+			objects:        []client.Object{issuer(oldFingerprint, true)},
+			provisioner:    &fakeProvisioner{caBundleErr: errors.New("access denied")},
+			expectedResult: ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(tc.objects...).
+				WithStatusSubresource(tc.objects...).
+				Build()
+
+			controller := GenericIssuerReconciler{
+				Client:             fakeClient,
+				Log:                logrtesting.NewTestLogger(t),
+				Scheme:             scheme,
+				Recorder:           record.NewFakeRecorder(10),
+				TrackCACertificate: true,
+			}
+
+			GetProvisioner = generateMockGetProvisioner(tc.provisioner, nil)
+			t.Cleanup(func() { GetProvisioner = awspca.GetProvisioner })
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			iss := new(issuerapi.AWSPCAIssuer)
+			require.NoError(t, fakeClient.Get(ctx, name, iss))
+			previous := iss.Status.CACertificateFingerprint
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResult, result, "Unexpected result")
+
+			require.NoError(t, fakeClient.Get(ctx, name, iss))
+			if tc.provisioner.caBundleErr != nil {
+				assert.Equal(t, previous, iss.Status.CACertificateFingerprint)
+			} else {
+				assert.Equal(t, fingerprint, iss.Status.CACertificateFingerprint)
+			}
+			assertIssuerHasReadyCondition(t, metav1.ConditionTrue, &iss.Status)
+			assert.Equal(t, tc.expectedRotatedCondition, meta.IsStatusConditionTrue(iss.Status.Conditions, issuerapi.ConditionTypeCACertificateRotated))
+
+			for _, crName := range tc.expectedRotatedRequests {
+				cr := new(cmapi.CertificateRequest)
+				require.NoError(t, fakeClient.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: crName}, cr))
+				assert.Equal(t, fingerprint, cr.GetAnnotations()[awspca.CARotatedAnnotation], "CertificateRequest %s not annotated", crName)
+			}
+			for _, crName := range tc.unexpectedRotatedRequests {
+				cr := new(cmapi.CertificateRequest)
+				require.NoError(t, fakeClient.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: crName}, cr))
+				assert.NotContains(t, cr.GetAnnotations(), awspca.CARotatedAnnotation, "CertificateRequest %s unexpectedly annotated", crName)
+			}
+
+			crt := new(cmapi.Certificate)
+			if err := fakeClient.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "crt1"}, crt); err == nil {
+				issuing := cmutil.CertificateHasCondition(crt, cmapi.CertificateCondition{
+					Type:   cmapi.CertificateConditionIssuing,
+					Status: cmmeta.ConditionTrue,
+				})
+				assert.Equal(t, tc.expectedReissue, issuing, "unexpected Issuing condition")
+				_, annotated := crt.GetAnnotations()[awspca.CARotatedAnnotation]
+				assert.Equal(t, tc.expectedReissue, annotated, "unexpected Certificate annotation")
+			}
+		})
+	}
+}
+
+func TestIssuerReconcile_RevocationCheck(t *testing.T) {
+	enabled := issuerapi.RevocationStatus{
+		OCSPEnabled: true,
+		OCSPURL:     "http://ocsp.acm-pca.us-east-1.amazonaws.com",
+	}
+
+	type testCase struct {
+		check                        issuerapi.RevocationCheck
+		requireRevocation            bool
+		provisioner                  *fakeProvisioner
+		expectedResult               ctrl.Result
+		expectedError                string
+		expectedReadyConditionStatus metav1.ConditionStatus
+		expectedRevocation           *issuerapi.RevocationStatus
+	}
+
+	tests := map[string]testCase{
+		"success-none": {
+			provisioner:                  &fakeProvisioner{describeErr: errors.New("unexpected call")},
+			expectedResult:               ctrl.Result{},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+		},
+		"success-report-disabled": {
+			check:                        issuerapi.RevocationCheckReport,
+			provisioner:                  &fakeProvisioner{ca: &awspca.CertificateAuthority{}},
+			expectedResult:               ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedRevocation:           &issuerapi.RevocationStatus{},
+		},
+		"success-require-enabled": {
+			check:                        issuerapi.RevocationCheckRequire,
+			provisioner:                  &fakeProvisioner{ca: &awspca.CertificateAuthority{Revocation: enabled}},
+			expectedResult:               ctrl.Result{RequeueAfter: defaultCARefreshInterval},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedRevocation:           &enabled,
+		},
+		"failure-require-disabled": {
+			check:                        issuerapi.RevocationCheckRequire,
+			provisioner:                  &fakeProvisioner{ca: &awspca.CertificateAuthority{}},
+			expectedError:                errRevocationNotConfigured.Error(),
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedRevocation:           &issuerapi.RevocationStatus{},
+		},
+		"failure-required-by-controller": {
+			check:                        issuerapi.RevocationCheckReport,
+			requireRevocation:            true,
+			provisioner:                  &fakeProvisioner{ca: &awspca.CertificateAuthority{}},
+			expectedError:                errRevocationNotConfigured.Error(),
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedRevocation:           &issuerapi.RevocationStatus{},
+		},
+		"failure-describe": {
+			check:                        issuerapi.RevocationCheckReport,
+			provisioner:                  &fakeProvisioner{describeErr: errors.New("access denied")},
+			expectedError:                "failed to describe CA: access denied",
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					Region:          "us-east-1",
+					Arn:             "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					RevocationCheck: tc.check,
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss).
+				WithStatusSubresource(iss).
+				Build()
+
+			controller := GenericIssuerReconciler{
+				Client:            fakeClient,
+				Log:               logrtesting.NewTestLogger(t),
+				Scheme:            scheme,
+				Recorder:          record.NewFakeRecorder(10),
+				RequireRevocation: tc.requireRevocation,
+			}
+
+			GetProvisioner = generateMockGetProvisioner(tc.provisioner, nil)
+			t.Cleanup(func() { GetProvisioner = awspca.GetProvisioner })
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			require.NoError(t, fakeClient.Get(ctx, name, iss))
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedResult, result, "Unexpected result")
+
+			require.NoError(t, fakeClient.Get(ctx, name, iss))
+			assertIssuerHasReadyCondition(t, tc.expectedReadyConditionStatus, &iss.Status)
+			assert.Equal(t, tc.expectedRevocation, iss.Status.Revocation)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// InstanceAnnotation assigns an issuer to the controller instance of the same name
+const InstanceAnnotation = "aws-privateca-issuer/instance"
+
+// IssuerFilter selects the issuers handled by a controller instance, so that
+// several instances can run in one cluster. The zero value handles every
+// issuer that is not assigned to a named instance.
+type IssuerFilter struct {
+	// Selector matches the labels of handled issuers. A nil Selector
+	// matches all issuers.
+	Selector labels.Selector
+	// InstanceName must equal the instance annotation of handled issuers
+	InstanceName string
+}
+
+// Matches returns whether the issuer is handled by this instance
+func (f IssuerFilter) Matches(issuer metav1.Object) bool {
+	if issuer.GetAnnotations()[InstanceAnnotation] != f.InstanceName {
+		return false
+	}
+	return f.Selector == nil || f.Selector.Matches(labels.Set(issuer.GetLabels()))
+}
+
+// Predicate filters the events of issuers not handled by this instance
+func (f IssuerFilter) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return f.Matches(obj)
+	})
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+func TestIssuerFilter(t *testing.T) {
+	type testCase struct {
+		filter      IssuerFilter
+		labels      map[string]string
+		annotations map[string]string
+		expected    bool
+	}
+
+	teamA := labels.SelectorFromSet(labels.Set{"team": "a"})
+
+	tests := map[string]testCase{
+		"zero-value-matches-unannotated": {
+			filter:   IssuerFilter{},
+			labels:   map[string]string{"team": "b"},
+			expected: true,
+		},
+		"zero-value-ignores-annotated": {
+			filter:      IssuerFilter{},
+			annotations: map[string]string{InstanceAnnotation: "a"},
+			expected:    false,
+		},
+		"instance-matches-annotation": {
+			filter:      IssuerFilter{InstanceName: "a"},
+			annotations: map[string]string{InstanceAnnotation: "a"},
+			expected:    true,
+		},
+		"instance-ignores-unannotated": {
+			filter:   IssuerFilter{InstanceName: "a"},
+			expected: false,
+		},
+		"selector-matches-labels": {
+			filter:   IssuerFilter{Selector: teamA},
+			labels:   map[string]string{"team": "a"},
+			expected: true,
+		},
+		"selector-ignores-labels": {
+			filter:   IssuerFilter{Selector: teamA},
+			labels:   map[string]string{"team": "b"},
+			expected: false,
+		},
+		"selector-and-instance": {
+			filter:      IssuerFilter{Selector: teamA, InstanceName: "a"},
+			labels:      map[string]string{"team": "a"},
+			annotations: map[string]string{InstanceAnnotation: "a"},
+			expected:    true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuer := &issuerapi.AWSPCAClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "issuer1",
+					Labels:      tc.labels,
+					Annotations: tc.annotations,
+				},
+			}
+			assert.Equal(t, tc.expected, tc.filter.Matches(issuer))
+			assert.Equal(t, tc.expected, tc.filter.Predicate().Generic(event.GenericEvent{Object: issuer}))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// issuerRefIndex indexes CertificateRequests by the issuer they refer to, in
+// the form returned by issuerRefKey
+const issuerRefIndex = "spec.issuerRef"
+
+// issuerRefKey identifies an issuer of the given kind. The namespace is
+// ignored for cluster issuers.
+func issuerRefKey(kind, namespace, name string) string {
+	if kind == util.ClusterIssuerKind {
+		return kind + "/" + name
+	}
+	return kind + "/" + namespace + "/" + name
+}
+
+// indexIssuerRef is the index function of issuerRefIndex
+func indexIssuerRef(obj client.Object) []string {
+	cr, ok := obj.(*cmapi.CertificateRequest)
+	if !ok || cr.Spec.IssuerRef.Group != api.GroupVersion.Group {
+		return nil
+	}
+	return []string{issuerRefKey(cr.Spec.IssuerRef.Kind, cr.Namespace, cr.Spec.IssuerRef.Name)}
+}
+
+// issuerBecameReady passes the issuers that are created Ready, or become
+// Ready
+var issuerBecameReady = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		iss, ok := e.Object.(api.GenericIssuer)
+		return ok && isReady(iss)
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldIss, ok := e.ObjectOld.(api.GenericIssuer)
+		if !ok {
+			return false
+		}
+		newIss, ok := e.ObjectNew.(api.GenericIssuer)
+		return ok && !isReady(oldIss) && isReady(newIss)
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// requestsForIssuer returns the CertificateRequests waiting for an issuer, so
+// that they are signed as soon as it is Ready rather than after their backoff
+func (r *CertificateRequestReconciler) requestsForIssuer(ctx context.Context, obj client.Object) []reconcile.Request {
+	iss, ok := obj.(api.GenericIssuer)
+	if !ok {
+		return nil
+	}
+
+	crList := new(cmapi.CertificateRequestList)
+	if err := r.Client.List(ctx, crList, client.MatchingFields{
+		issuerRefIndex: issuerRefKey(issuerKind(iss), iss.GetNamespace(), iss.GetName()),
+	}); err != nil {
+		r.Log.Error(err, "failed to list the CertificateRequests of issuer", "issuer", client.ObjectKeyFromObject(iss))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range crList.Items {
+		cr := &crList.Items[i]
+		if isFinished(cr) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cr)})
+	}
+	return requests
+}
+
+// isFinished returns whether a CertificateRequest is Ready, Failed or Denied,
+// so that reconciling it again has no effect
+func isFinished(cr *cmapi.CertificateRequest) bool {
+	condition := cmutil.GetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionReady)
+	if condition == nil {
+		return false
+	}
+	return condition.Status == cmmeta.ConditionTrue ||
+		condition.Reason == cmapi.CertificateRequestReasonFailed ||
+		condition.Reason == cmapi.CertificateRequestReasonDenied
+}
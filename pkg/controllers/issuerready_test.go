@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRequestsForIssuer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	request := func(namespace, name, kind, issuer string, mods ...cmgen.CertificateRequestModifier) *cmapi.CertificateRequest {
+		mods = append(mods,
+			cmgen.SetCertificateRequestNamespace(namespace),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  issuer,
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  kind,
+			}))
+		return cmgen.CertificateRequest(name, mods...)
+	}
+	ready := func(reason string) cmgen.CertificateRequestModifier {
+		status := cmmeta.ConditionFalse
+		if reason == cmapi.CertificateRequestReasonIssued {
+			status = cmmeta.ConditionTrue
+		}
+		return cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+			Type:   cmapi.CertificateRequestConditionReady,
+			Status: status,
+			Reason: reason,
+		})
+	}
+
+	objects := []client.Object{
+		request("ns1", "pending", "AWSPCAIssuer", "issuer1", ready(cmapi.CertificateRequestReasonPending)),
+		request("ns1", "new", "AWSPCAIssuer", "issuer1"),
+		request("ns1", "issued", "AWSPCAIssuer", "issuer1", ready(cmapi.CertificateRequestReasonIssued)),
+		request("ns1", "failed", "AWSPCAIssuer", "issuer1", ready(cmapi.CertificateRequestReasonFailed)),
+		request("ns1", "other-issuer", "AWSPCAIssuer", "issuer2"),
+		request("ns2", "other-namespace", "AWSPCAIssuer", "issuer1"),
+		request("ns1", "cluster", "AWSPCAClusterIssuer", "issuer1"),
+		request("ns2", "cluster", "AWSPCAClusterIssuer", "issuer1"),
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithIndex(&cmapi.CertificateRequest{}, issuerRefIndex, indexIssuerRef).
+		Build()
+	r := &CertificateRequestReconciler{Client: fakeClient, Log: logrtesting.NewTestLogger(t)}
+
+	issuer := &issuerapi.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "issuer1"}}
+	assert.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "pending"}},
+		{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "new"}},
+	}, r.requestsForIssuer(context.TODO(), issuer))
+
+	clusterIssuer := &issuerapi.AWSPCAClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer1"}}
+	assert.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "cluster"}},
+		{NamespacedName: types.NamespacedName{Namespace: "ns2", Name: "cluster"}},
+	}, r.requestsForIssuer(context.TODO(), clusterIssuer))
+}
+
+func TestIssuerBecameReady(t *testing.T) {
+	issuer := func(status metav1.ConditionStatus) *issuerapi.AWSPCAIssuer {
+		return &issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "issuer1"},
+			Status: issuerapi.AWSPCAIssuerStatus{
+				Conditions: []metav1.Condition{{Type: issuerapi.ConditionTypeReady, Status: status}},
+			},
+		}
+	}
+
+	assert.True(t, issuerBecameReady.Create(event.CreateEvent{Object: issuer(metav1.ConditionTrue)}))
+	assert.False(t, issuerBecameReady.Create(event.CreateEvent{Object: issuer(metav1.ConditionFalse)}))
+	assert.True(t, issuerBecameReady.Update(event.UpdateEvent{ObjectOld: issuer(metav1.ConditionFalse), ObjectNew: issuer(metav1.ConditionTrue)}))
+	assert.False(t, issuerBecameReady.Update(event.UpdateEvent{ObjectOld: issuer(metav1.ConditionTrue), ObjectNew: issuer(metav1.ConditionTrue)}))
+	assert.False(t, issuerBecameReady.Update(event.UpdateEvent{ObjectOld: issuer(metav1.ConditionTrue), ObjectNew: issuer(metav1.ConditionFalse)}))
+	assert.False(t, issuerBecameReady.Delete(event.DeleteEvent{Object: issuer(metav1.ConditionTrue)}))
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var errRevocationNotConfigured = errors.New("the CA has neither a CRL nor OCSP enabled")
+
+// revocationCheck returns how the revocation configuration of the CA of an
+// issuer is checked
+func (r *GenericIssuerReconciler) revocationCheck(spec *api.AWSPCAIssuerSpec) api.RevocationCheck {
+	if r.RequireRevocation {
+		return api.RevocationCheckRequire
+	}
+	if spec.RevocationCheck == "" {
+		return api.RevocationCheckNone
+	}
+	return spec.RevocationCheck
+}
+
+// checkRevocation records the revocation configuration of the CA in the
+// issuer status, and fails if the CA is required to have revocation
+// configured but has not
+func (r *GenericIssuerReconciler) checkRevocation(ctx context.Context, req ctrl.Request, issuer api.GenericIssuer) error {
+	spec := issuer.GetSpec()
+	check := r.revocationCheck(spec)
+	if check == api.RevocationCheckNone {
+		issuer.GetStatus().Revocation = nil
+		return nil
+	}
+
+	provisioner, err := GetProvisioner(ctx, r.Client, req.NamespacedName, spec)
+	if err != nil {
+		return err
+	}
+	ca, err := provisioner.DescribeCertificateAuthority(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to describe CA: %w", err)
+	}
+
+	revocation := ca.Revocation
+	issuer.GetStatus().Revocation = &revocation
+	if check == api.RevocationCheckRequire && !revocation.CRLEnabled && !revocation.OCSPEnabled {
+		return errRevocationNotConfigured
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/sharding"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// requestsForShard returns the unfinished CertificateRequests of a shard
+// acquired by this replica. They were ignored while another replica held the
+// shard.
+func (r *CertificateRequestReconciler) requestsForShard(ctx context.Context, obj client.Object) []reconcile.Request {
+	shard, ok := sharding.ShardOf(obj)
+	if !ok {
+		return nil
+	}
+
+	crList := new(cmapi.CertificateRequestList)
+	if err := r.Client.List(ctx, crList); err != nil {
+		r.Log.Error(err, "failed to list CertificateRequests of acquired shard", "shard", shard)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range crList.Items {
+		cr := &crList.Items[i]
+		key := types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}
+		if cr.Spec.IssuerRef.Group != api.GroupVersion.Group || isFinished(cr) || sharding.Shard(key, r.Sharder.Shards()) != shard {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+	return requests
+}
+
+// refreshProvisioners refreshes the cached provisioner of an issuer that
+// changed, and drops the one of an issuer that was deleted. The issuer
+// controllers do this on the leader, but with sharding every replica signs
+// with its own provisioners. Resyncs refresh too, so that rotated credentials
+// are picked up as on the leader.
+func (r *CertificateRequestReconciler) refreshProvisioners() handler.Funcs {
+	return handler.Funcs{
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			issuer, ok := e.ObjectNew.(api.GenericIssuer)
+			if !ok {
+				return
+			}
+			version, err := awspca.ConfigVersion(ctx, r.Client, issuer.GetGeneration(), issuer.GetSpec())
+			if err != nil {
+				r.Log.Error(err, "failed to refresh provisioner", "issuer", client.ObjectKeyFromObject(issuer))
+				return
+			}
+			awspca.RefreshProvisioner(client.ObjectKeyFromObject(issuer), version)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			awspca.DeleteProvisioner(ctx, r.Client, client.ObjectKeyFromObject(e.Object))
+		},
+	}
+}
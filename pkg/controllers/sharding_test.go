@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/sharding"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordination "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestShardedCertificateRequests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	const shards = 4
+	var objects []client.Object
+	var inShard []reconcile.Request
+	for i := 0; i < 16; i++ {
+		name := fmt.Sprintf("cr%d", i)
+		mods := []cmgen.CertificateRequestModifier{
+			cmgen.SetCertificateRequestNamespace("ns1"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "issuer1",
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  "AWSPCAIssuer",
+			}),
+		}
+		issued := i%4 == 0
+		if issued {
+			mods = append(mods, cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+				Type:   cmapi.CertificateRequestConditionReady,
+				Status: cmmeta.ConditionTrue,
+				Reason: cmapi.CertificateRequestReasonIssued,
+			}))
+		}
+		objects = append(objects, cmgen.CertificateRequest(name, mods...))
+
+		key := types.NamespacedName{Namespace: "ns1", Name: name}
+		if !issued && sharding.Shard(key, shards) == 1 {
+			inShard = append(inShard, reconcile.Request{NamespacedName: key})
+		}
+	}
+	require.NotEmpty(t, inShard)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	r := &CertificateRequestReconciler{
+		Client:   fakeClient,
+		Log:      logrtesting.NewTestLogger(t),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Sharder: sharding.New(fakeClient, logrtesting.NewTestLogger(t), sharding.Options{
+			Name:          "awspca",
+			Namespace:     "cert-manager",
+			Identity:      "a",
+			Shards:        shards,
+			LeaseDuration: 15 * time.Second,
+			RenewPeriod:   5 * time.Second,
+		}),
+	}
+
+	lease := &coordination.Lease{ObjectMeta: metav1.ObjectMeta{
+		Name:   "awspca-shard-1",
+		Labels: map[string]string{sharding.ShardLabel: "1"},
+	}}
+	assert.ElementsMatch(t, inShard, r.requestsForShard(context.TODO(), lease))
+
+	// No shard is held, so the CertificateRequest is left alone
+	result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: inShard[0].NamespacedName})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+
+	cr := new(cmapi.CertificateRequest)
+	require.NoError(t, fakeClient.Get(context.TODO(), inShard[0].NamespacedName, cr))
+	assert.Empty(t, cr.Status.Conditions)
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	defaultTrustBundleKey    = "ca.crt"
+	defaultCARefreshInterval = time.Hour
+)
+
+var (
+	errNoTrustBundleName              = errors.New("no name found in trust bundle")
+	errNoTrustBundleNamespaceSelector = errors.New("a namespaceSelector is required for the trust bundle of an AWSPCAClusterIssuer")
+	errTrustBundleNamespaceSelector   = errors.New("a namespaceSelector is not supported for the trust bundle of an AWSPCAIssuer")
+	errTrustBundleNotControlled       = errors.New("trust bundle exists and is not controlled by the issuer")
+)
+
+// Writing Secrets is only granted by the Helm chart with rbac.trustBundleSecrets,
+// for issuers that publish their trust bundle to a Secret.
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+func isClusterIssuer(issuer api.GenericIssuer) bool {
+	return issuer.GetNamespace() == ""
+}
+
+// issuerKind returns the kind of issuer
+func issuerKind(issuer api.GenericIssuer) string {
+	if isClusterIssuer(issuer) {
+		return util.ClusterIssuerKind
+	}
+	return util.IssuerKind
+}
+
+func validateTrustBundle(issuer api.GenericIssuer) error {
+	bundle := issuer.GetSpec().TrustBundle
+	if bundle == nil {
+		return nil
+	}
+	switch {
+	case bundle.Name == "":
+		return errNoTrustBundleName
+	case isClusterIssuer(issuer) && bundle.NamespaceSelector == nil:
+		return errNoTrustBundleNamespaceSelector
+	case !isClusterIssuer(issuer) && bundle.NamespaceSelector != nil:
+		return errTrustBundleNamespaceSelector
+	}
+	return nil
+}
+
+// publishTrustBundle writes the CA certificate and chain to the trust bundle
+// of the issuer in every selected namespace. A namespace that fails does not
+// keep the bundle from being published to the others, and the outcome is
+// recorded in the TrustBundlePublished condition.
+func (r *GenericIssuerReconciler) publishTrustBundle(ctx context.Context, issuer api.GenericIssuer, bundle []byte) error {
+	namespaces, err := r.trustBundleNamespaces(ctx, issuer)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, namespace := range namespaces {
+		if err := r.writeTrustBundle(ctx, issuer, namespace, bundle); err != nil {
+			if errors.Is(err, errTrustBundleNotControlled) {
+				r.Recorder.Event(issuer, core.EventTypeWarning, "TrustBundleConflict", err.Error())
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	err = errors.Join(errs...)
+	switch {
+	case err == nil:
+		util.SetIssuerCondition(r.Log, issuer, api.ConditionTypeTrustBundlePublished, metav1.ConditionTrue, "Published",
+			fmt.Sprintf("Trust bundle published to %d namespaces", len(namespaces)))
+	case errors.Is(err, errTrustBundleNotControlled):
+		util.SetIssuerCondition(r.Log, issuer, api.ConditionTypeTrustBundlePublished, metav1.ConditionFalse, "Conflict", err.Error())
+	default:
+		util.SetIssuerCondition(r.Log, issuer, api.ConditionTypeTrustBundlePublished, metav1.ConditionFalse, "Error", err.Error())
+	}
+	if statusErr := r.Client.Status().Update(ctx, issuer); statusErr != nil {
+		errs = append(errs, statusErr)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *GenericIssuerReconciler) trustBundleNamespaces(ctx context.Context, issuer api.GenericIssuer) ([]string, error) {
+	if !isClusterIssuer(issuer) {
+		return []string{issuer.GetNamespace()}, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(issuer.GetSpec().TrustBundle.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust bundle namespaceSelector: %w", err)
+	}
+
+	namespaceList := new(core.NamespaceList)
+	if err := r.Client.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	namespaces := make([]string, 0, len(namespaceList.Items))
+	for _, namespace := range namespaceList.Items {
+		namespaces = append(namespaces, namespace.Name)
+	}
+	return namespaces, nil
+}
+
+// writeTrustBundle creates the trust bundle in a namespace, or updates it if
+// it is controlled by the issuer. Objects of the same name that the issuer
+// does not control are never adopted, so that they are not overwritten, nor
+// deleted together with the issuer.
+func (r *GenericIssuerReconciler) writeTrustBundle(ctx context.Context, issuer api.GenericIssuer, namespace string, bundle []byte) error {
+	trustBundle := issuer.GetSpec().TrustBundle
+	key := defaultTrustBundleKey
+	if trustBundle.Key != "" {
+		key = trustBundle.Key
+	}
+	name := client.ObjectKey{Namespace: namespace, Name: trustBundle.Name}
+
+	var (
+		obj    client.Object
+		mutate func() bool
+	)
+	switch trustBundle.Kind {
+	case api.TrustBundleKindSecret:
+		secret := new(core.Secret)
+		obj, mutate = secret, func() bool {
+			if bytes.Equal(secret.Data[key], bundle) {
+				return false
+			}
+			if secret.Data == nil {
+				secret.Data = map[string][]byte{}
+			}
+			secret.Data[key] = bundle
+			return true
+		}
+	default:
+		configMap := new(core.ConfigMap)
+		obj, mutate = configMap, func() bool {
+			if value, ok := configMap.Data[key]; ok && value == string(bundle) {
+				return false
+			}
+			if configMap.Data == nil {
+				configMap.Data = map[string]string{}
+			}
+			configMap.Data[key] = string(bundle)
+			return true
+		}
+	}
+
+	err := r.Client.Get(ctx, name, obj)
+	switch {
+	case apierrors.IsNotFound(err):
+		obj.SetNamespace(namespace)
+		obj.SetName(trustBundle.Name)
+		mutate()
+		if err := controllerutil.SetControllerReference(issuer, obj, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Client.Create(ctx, obj); err != nil {
+			return fmt.Errorf("failed to create trust bundle %s: %w", name, err)
+		}
+		r.Recorder.Eventf(issuer, core.EventTypeNormal, "TrustBundlePublished", "Trust bundle created in %s", name)
+	case err != nil:
+		return fmt.Errorf("failed to get trust bundle %s: %w", name, err)
+	case !metav1.IsControlledBy(obj, issuer):
+		return fmt.Errorf("%w: %s", errTrustBundleNotControlled, name)
+	case mutate():
+		if err := r.Client.Update(ctx, obj); err != nil {
+			return fmt.Errorf("failed to update trust bundle %s: %w", name, err)
+		}
+		r.Recorder.Eventf(issuer, core.EventTypeNormal, "TrustBundlePublished", "Trust bundle updated in %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultProbeInterval is how long the result of the identity probe is reused
+	DefaultProbeInterval = time.Minute
+	// DefaultIssuerWindow is how long all issuers may fail before the
+	// controller reports unready
+	DefaultIssuerWindow = 10 * time.Minute
+)
+
+// IssuerHealth is the health of an issuer as last observed by its reconciler
+type IssuerHealth struct {
+	Kind         string     `json:"kind"`
+	Namespace    string     `json:"namespace,omitempty"`
+	Name         string     `json:"name"`
+	Healthy      bool       `json:"healthy"`
+	Message      string     `json:"message,omitempty"`
+	LastChecked  time.Time  `json:"lastChecked"`
+	LastVerified *time.Time `json:"lastVerified,omitempty"`
+}
+
+type issuerKey struct {
+	kind string
+	name types.NamespacedName
+}
+
+// Checker reports the controller unready when it cannot reach AWS with its
+// default identity, or when every issuer has been failing for longer than
+// IssuerWindow.
+type Checker struct {
+	// Probe checks the AWS connectivity of the default identity. The probe
+	// is skipped if nil.
+	Probe func(ctx context.Context) error
+	// ProbeInterval is how long a probe result is reused. Defaults to
+	// DefaultProbeInterval.
+	ProbeInterval time.Duration
+	// IssuerWindow is how long all issuers may fail before the controller
+	// reports unready. The check is disabled if zero.
+	IssuerWindow time.Duration
+	// Clock defaults to the real clock
+	Clock clock.PassiveClock
+
+	mu      sync.Mutex
+	issuers map[issuerKey]*IssuerHealth
+
+	// probeMu serializes probes without blocking issuer updates
+	probeMu   sync.Mutex
+	lastProbe time.Time
+	probeErr  error
+}
+
+func (c *Checker) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+// RecordIssuer records the outcome of reconciling an issuer
+func (c *Checker) RecordIssuer(kind string, name types.NamespacedName, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.issuers == nil {
+		c.issuers = make(map[issuerKey]*IssuerHealth)
+	}
+	key := issuerKey{kind: kind, name: name}
+	h, ok := c.issuers[key]
+	if !ok {
+		h = &IssuerHealth{Kind: kind, Namespace: name.Namespace, Name: name.Name}
+		c.issuers[key] = h
+	}
+
+	now := c.now()
+	h.LastChecked = now
+	h.Healthy = err == nil
+	h.Message = ""
+	if err != nil {
+		h.Message = err.Error()
+	} else {
+		h.LastVerified = &now
+	}
+}
+
+// ForgetIssuer stops tracking a deleted issuer
+func (c *Checker) ForgetIssuer(kind string, name types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.issuers, issuerKey{kind: kind, name: name})
+}
+
+// Issuers returns the health of all tracked issuers, sorted by kind,
+// namespace and name
+func (c *Checker) Issuers() []IssuerHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	issuers := make([]IssuerHealth, 0, len(c.issuers))
+	for _, h := range c.issuers {
+		issuers = append(issuers, *h)
+	}
+	sort.Slice(issuers, func(i, j int) bool {
+		a, b := issuers[i], issuers[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return issuers
+}
+
+// Check implements healthz.Checker
+func (c *Checker) Check(req *http.Request) error {
+	if err := c.probe(req.Context()); err != nil {
+		return fmt.Errorf("failed to reach AWS with the default identity: %w", err)
+	}
+	return c.checkIssuers()
+}
+
+func (c *Checker) probe(ctx context.Context) error {
+	if c.Probe == nil {
+		return nil
+	}
+
+	c.probeMu.Lock()
+	defer c.probeMu.Unlock()
+
+	interval := c.ProbeInterval
+	if interval == 0 {
+		interval = DefaultProbeInterval
+	}
+	if now := c.now(); c.lastProbe.IsZero() || now.Sub(c.lastProbe) >= interval {
+		c.probeErr = c.Probe(ctx)
+		c.lastProbe = now
+	}
+	return c.probeErr
+}
+
+// checkIssuers fails if issuers are tracked but none is healthy or has been
+// verified within the issuer window
+func (c *Checker) checkIssuers() error {
+	if c.IssuerWindow == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.issuers) == 0 {
+		return nil
+	}
+	since := c.now().Add(-c.IssuerWindow)
+	for _, h := range c.issuers {
+		if h.Healthy || (h.LastVerified != nil && h.LastVerified.After(since)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("none of the %d issuers has been verified in the last %s", len(c.issuers), c.IssuerWindow)
+}
+
+// ServeHTTP serves the health of all tracked issuers as JSON
+func (c *Checker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Issuers()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var (
+	issuer1 = types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	issuer2 = types.NamespacedName{Name: "issuer2"}
+)
+
+func TestCheckerIssuers(t *testing.T) {
+	type testCase struct {
+		record        func(*Checker, *clocktesting.FakeClock)
+		expectFailure bool
+	}
+
+	failure := errors.New("AccessDenied")
+
+	tests := map[string]testCase{
+		"no-issuers": {
+			record: func(*Checker, *clocktesting.FakeClock) {},
+		},
+		"healthy": {
+			record: func(c *Checker, _ *clocktesting.FakeClock) {
+				c.RecordIssuer("AWSPCAIssuer", issuer1, nil)
+			},
+		},
+		"never-verified": {
+			record: func(c *Checker, _ *clocktesting.FakeClock) {
+				c.RecordIssuer("AWSPCAIssuer", issuer1, failure)
+			},
+			expectFailure: true,
+		},
+		"failing-within-window": {
+			record: func(c *Checker, clock *clocktesting.FakeClock) {
+				c.RecordIssuer("AWSPCAIssuer", issuer1, nil)
+				clock.Step(5 * time.Minute)
+				c.RecordIssuer("AWSPCAIssuer", issuer1, failure)
+			},
+		},
+		"failing-beyond-window": {
+			record: func(c *Checker, clock *clocktesting.FakeClock) {
+				c.RecordIssuer("AWSPCAIssuer", issuer1, nil)
+				clock.Step(15 * time.Minute)
+				c.RecordIssuer("AWSPCAIssuer", issuer1, failure)
+			},
+			expectFailure: true,
+		},
+		"one-of-two-healthy": {
+			record: func(c *Checker, _ *clocktesting.FakeClock) {
+				c.RecordIssuer("AWSPCAIssuer", issuer1, failure)
+				c.RecordIssuer("AWSPCAClusterIssuer", issuer2, nil)
+			},
+		},
+		"failing-issuer-forgotten": {
+			record: func(c *Checker, _ *clocktesting.FakeClock) {
+				c.RecordIssuer("AWSPCAIssuer", issuer1, failure)
+				c.ForgetIssuer("AWSPCAIssuer", issuer1)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			clock := clocktesting.NewFakeClock(time.Now())
+			checker := &Checker{IssuerWindow: DefaultIssuerWindow, Clock: clock}
+			tc.record(checker, clock)
+
+			err := checker.Check(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if tc.expectFailure {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckerProbe(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	calls := 0
+	probeErr := errors.New("no EC2 IMDS role found")
+	checker := &Checker{
+		Clock: clock,
+		Probe: func(context.Context) error {
+			calls++
+			return probeErr
+		},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	assert.ErrorIs(t, checker.Check(req), probeErr)
+	assert.Equal(t, 1, calls)
+
+	probeErr = nil
+	clock.Step(DefaultProbeInterval / 2)
+	assert.Error(t, checker.Check(req), "probe result should be reused within the interval")
+	assert.Equal(t, 1, calls)
+
+	clock.Step(DefaultProbeInterval)
+	assert.NoError(t, checker.Check(req))
+	assert.Equal(t, 2, calls)
+}
+
+func TestCheckerServeHTTP(t *testing.T) {
+	checker := &Checker{}
+	checker.RecordIssuer("AWSPCAIssuer", issuer1, errors.New("AccessDenied"))
+	checker.RecordIssuer("AWSPCAClusterIssuer", issuer2, nil)
+
+	rec := httptest.NewRecorder()
+	checker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/issuers", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var issuers []IssuerHealth
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &issuers))
+	require.Len(t, issuers, 2)
+	assert.Equal(t, "issuer2", issuers[0].Name)
+	assert.True(t, issuers[0].Healthy)
+	assert.NotNil(t, issuers[0].LastVerified)
+	assert.Equal(t, "issuer1", issuers[1].Name)
+	assert.False(t, issuers[1].Healthy)
+	assert.Equal(t, "AccessDenied", issuers[1].Message)
+	assert.Nil(t, issuers[1].LastVerified)
+}
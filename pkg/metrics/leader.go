@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the Prometheus metrics of the issuer, which are
+// served on the metrics endpoint of the controller manager.
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Leader is 1 while this replica leads, and 0 while it waits for the lease.
+// Unlike leader_election_master_status, it is reported by every replica.
+var Leader = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "awspca_issuer_leader",
+	Help: "Whether this replica is the leader, 1 while it holds the leader election lease and 0 otherwise.",
+})
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(Leader)
+}
+
+// LeaderRunnable sets the Leader gauge while the manager is the leader. It
+// only runs once the manager is elected, or right away without leader
+// election.
+type LeaderRunnable struct{}
+
+// Start implements manager.Runnable
+func (LeaderRunnable) Start(ctx context.Context) error {
+	Leader.Set(1)
+	<-ctx.Done()
+	Leader.Set(0)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (LeaderRunnable) NeedLeaderElection() bool {
+	return true
+}
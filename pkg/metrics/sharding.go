@@ -0,0 +1,33 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ShardsOwned is the number of CertificateRequest shards held by this
+// replica when sharding is enabled
+var ShardsOwned = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "awspca_issuer_shards_owned",
+	Help: "The number of CertificateRequest shards held by this replica.",
+})
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(ShardsOwned)
+}
@@ -0,0 +1,434 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding spreads the CertificateRequests handled by the controller
+// over its replicas. Every replica renews a member lease, the shards are
+// assigned to the live members by rendezvous hashing, and a replica only
+// handles the CertificateRequests of a shard while it holds the lease of that
+// shard.
+package sharding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/aws-privateca-issuer/pkg/metrics"
+	"github.com/go-logr/logr"
+	coordination "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// ShardLabel holds the shard of a shard lease
+const ShardLabel = "aws-privateca-issuer/shard"
+
+// inClusterNamespacePath holds the namespace of the controller when it runs
+// in a pod
+const inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Options configures a Sharder
+type Options struct {
+	// Name prefixes the names of the shard and member leases
+	Name string
+	// Namespace the leases are created in
+	Namespace string
+	// Identity of this replica, unique among the replicas
+	Identity string
+	// Shards is the number of shards CertificateRequests are spread over
+	Shards int
+	// LeaseDuration is how long a lease that is not renewed is held
+	LeaseDuration time.Duration
+	// RenewPeriod is how often the leases are renewed and the shards
+	// reassigned
+	RenewPeriod time.Duration
+}
+
+// Sharder claims the shards assigned to this replica
+type Sharder struct {
+	client client.Client
+	log    logr.Logger
+	opts   Options
+	now    func() time.Time
+
+	mu sync.Mutex
+	// owned maps the shards held by this replica to the local time their
+	// claim lapses at, unless it is renewed
+	owned map[int]time.Time
+	// observed records when the leases of other replicas last changed
+	observed map[string]observation
+	gained   chan event.GenericEvent
+}
+
+// observation is the resourceVersion of a lease and the local time it was
+// first seen at. Leases are expired by the local clock, so that the clocks of
+// the replicas do not need to agree.
+type observation struct {
+	resourceVersion string
+	at              time.Time
+}
+
+// New returns a Sharder for the replica opts.Identity
+func New(c client.Client, log logr.Logger, opts Options) *Sharder {
+	return &Sharder{
+		client:   c,
+		log:      log,
+		opts:     opts,
+		now:      time.Now,
+		owned:    make(map[int]time.Time),
+		observed: make(map[string]observation),
+		gained:   make(chan event.GenericEvent, opts.Shards),
+	}
+}
+
+// Shard returns the shard of the CertificateRequest key out of shards
+func Shard(key types.NamespacedName, shards int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key.Namespace + "/" + key.Name))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// Shards returns the number of shards
+func (s *Sharder) Shards() int {
+	return s.opts.Shards
+}
+
+// Owns reports whether this replica holds the shard of the CertificateRequest
+// key
+func (s *Sharder) Owns(key types.NamespacedName) bool {
+	shard := Shard(key, s.opts.Shards)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now().Before(s.owned[shard])
+}
+
+// Gained receives the lease of every shard this replica acquires, so that the
+// CertificateRequests of the shard can be reconciled
+func (s *Sharder) Gained() <-chan event.GenericEvent {
+	return s.gained
+}
+
+// ShardOf returns the shard of a lease received from Gained
+func ShardOf(obj client.Object) (int, bool) {
+	shard, err := strconv.Atoi(obj.GetLabels()[ShardLabel])
+	return shard, err == nil
+}
+
+// Start implements manager.Runnable. It claims the shards of this replica
+// every RenewPeriod, and releases them when ctx is done.
+func (s *Sharder) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.RenewPeriod)
+	defer ticker.Stop()
+
+	for {
+		s.sync(ctx)
+		select {
+		case <-ctx.Done():
+			stopCtx, cancel := context.WithTimeout(context.Background(), s.opts.RenewPeriod)
+			defer cancel()
+			s.stop(stopCtx)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every
+// replica takes part in sharding.
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// sync renews the member lease of this replica, acquires or renews the leases
+// of the shards assigned to it and releases those assigned to other replicas
+func (s *Sharder) sync(ctx context.Context) {
+	defer s.recordShardsOwned()
+
+	start := s.now()
+	if err := s.renewMember(ctx, start); err != nil {
+		// The claims lapse unless the member lease is renewed in time
+		s.log.Error(err, "failed to renew member lease")
+		return
+	}
+
+	leaseList := new(coordination.LeaseList)
+	if err := s.client.List(ctx, leaseList, client.InNamespace(s.opts.Namespace)); err != nil {
+		s.log.Error(err, "failed to list leases")
+		return
+	}
+	leases := make(map[string]*coordination.Lease, len(leaseList.Items))
+	for i := range leaseList.Items {
+		if strings.HasPrefix(leaseList.Items[i].Name, s.opts.Name+"-") {
+			leases[leaseList.Items[i].Name] = &leaseList.Items[i]
+		}
+	}
+	s.observe(leases, start)
+
+	members := s.members(ctx, leases, start)
+	for shard := 0; shard < s.opts.Shards; shard++ {
+		lease := leases[s.shardLeaseName(shard)]
+		var err error
+		switch {
+		case owner(shard, members) == s.opts.Identity:
+			err = s.acquire(ctx, shard, lease, start)
+		case lease != nil && holder(lease) == s.opts.Identity:
+			err = s.release(ctx, shard, lease)
+		}
+		if err != nil {
+			s.log.V(1).Info("failed to update shard lease", "shard", shard, "error", err.Error())
+		}
+	}
+}
+
+// recordShardsOwned sets the ShardsOwned metric to the number of shards whose
+// claim has not lapsed
+func (s *Sharder) recordShardsOwned() {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owned := 0
+	for _, expiry := range s.owned {
+		if now.Before(expiry) {
+			owned++
+		}
+	}
+	metrics.ShardsOwned.Set(float64(owned))
+}
+
+// renewMember creates or renews the member lease of this replica
+func (s *Sharder) renewMember(ctx context.Context, now time.Time) error {
+	lease := new(coordination.Lease)
+	key := client.ObjectKey{Namespace: s.opts.Namespace, Name: s.memberLeaseName(s.opts.Identity)}
+	if err := s.client.Get(ctx, key, lease); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		lease.ObjectMeta = metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}
+		lease.Spec = s.leaseSpec(now)
+		return s.client.Create(ctx, lease)
+	}
+	lease.Spec.HolderIdentity = ptr.To(s.opts.Identity)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.opts.LeaseDuration / time.Second))
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+	return s.client.Update(ctx, lease)
+}
+
+// members returns the identities of the live replicas, and deletes the member
+// leases of the replicas that are gone
+func (s *Sharder) members(ctx context.Context, leases map[string]*coordination.Lease, now time.Time) []string {
+	members := []string{s.opts.Identity}
+	for name, lease := range leases {
+		if !strings.HasPrefix(name, s.memberLeaseName("")) || holder(lease) == s.opts.Identity {
+			continue
+		}
+		if s.expired(lease, now) {
+			s.log.Info("removing replica whose member lease expired", "member", holder(lease))
+			if err := s.client.Delete(ctx, lease, client.Preconditions{ResourceVersion: &lease.ResourceVersion}); client.IgnoreNotFound(err) != nil {
+				s.log.V(1).Info("failed to delete member lease", "lease", name, "error", err.Error())
+			}
+			continue
+		}
+		if member := holder(lease); member != "" {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// acquire creates, renews or takes over the lease of a shard assigned to this
+// replica. A lease held by another replica is only taken over once it was
+// released or has expired.
+func (s *Sharder) acquire(ctx context.Context, shard int, lease *coordination.Lease, now time.Time) error {
+	switch {
+	case lease == nil:
+		lease = &coordination.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.opts.Namespace,
+				Name:      s.shardLeaseName(shard),
+				Labels:    map[string]string{ShardLabel: strconv.Itoa(shard)},
+			},
+			Spec: s.leaseSpec(now),
+		}
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		if err := s.client.Create(ctx, lease); err != nil {
+			return err
+		}
+	case holder(lease) == s.opts.Identity:
+		lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.opts.LeaseDuration / time.Second))
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		if err := s.client.Update(ctx, lease); err != nil {
+			return err
+		}
+	case holder(lease) == "" || s.expired(lease, now):
+		transitions := ptr.Deref(lease.Spec.LeaseTransitions, 0) + 1
+		lease.Spec = s.leaseSpec(now)
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		lease.Spec.LeaseTransitions = &transitions
+		if err := s.client.Update(ctx, lease); err != nil {
+			return err
+		}
+	default:
+		// Still held by the replica the shard was assigned to before
+		return nil
+	}
+
+	s.mu.Lock()
+	gained := !now.Before(s.owned[shard])
+	s.owned[shard] = now.Add(s.opts.LeaseDuration)
+	s.mu.Unlock()
+
+	if gained {
+		s.log.Info("acquired shard", "shard", shard)
+		select {
+		case s.gained <- event.GenericEvent{Object: lease}:
+		default:
+			s.log.Info("dropped shard acquisition, its CertificateRequests are reconciled on the next resync", "shard", shard)
+		}
+	}
+	return nil
+}
+
+// release gives up the lease of a shard that is assigned to another replica.
+// The claim is dropped before the lease is released, so that the shard is
+// never handled by two replicas at once.
+func (s *Sharder) release(ctx context.Context, shard int, lease *coordination.Lease) error {
+	s.mu.Lock()
+	delete(s.owned, shard)
+	s.mu.Unlock()
+
+	s.log.Info("releasing shard", "shard", shard)
+	lease.Spec.HolderIdentity = nil
+	return s.client.Update(ctx, lease)
+}
+
+// stop releases the leases held by this replica, so that its shards are
+// taken over without waiting for them to expire
+func (s *Sharder) stop(ctx context.Context) {
+	s.mu.Lock()
+	shards := make([]int, 0, len(s.owned))
+	for shard := range s.owned {
+		shards = append(shards, shard)
+	}
+	s.mu.Unlock()
+
+	for _, shard := range shards {
+		lease := new(coordination.Lease)
+		key := client.ObjectKey{Namespace: s.opts.Namespace, Name: s.shardLeaseName(shard)}
+		if err := s.client.Get(ctx, key, lease); err != nil || holder(lease) != s.opts.Identity {
+			continue
+		}
+		if err := s.release(ctx, shard, lease); err != nil {
+			s.log.Error(err, "failed to release shard", "shard", shard)
+		}
+	}
+
+	member := &coordination.Lease{ObjectMeta: metav1.ObjectMeta{
+		Namespace: s.opts.Namespace,
+		Name:      s.memberLeaseName(s.opts.Identity),
+	}}
+	if err := s.client.Delete(ctx, member); client.IgnoreNotFound(err) != nil {
+		s.log.Error(err, "failed to delete member lease")
+	}
+	metrics.ShardsOwned.Set(0)
+}
+
+// observe records the local time each lease last changed at, and forgets
+// the leases that were deleted
+func (s *Sharder) observe(leases map[string]*coordination.Lease, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	observed := make(map[string]observation, len(leases))
+	for name, lease := range leases {
+		seen, ok := s.observed[name]
+		if !ok || seen.resourceVersion != lease.ResourceVersion {
+			seen = observation{resourceVersion: lease.ResourceVersion, at: now}
+		}
+		observed[name] = seen
+	}
+	s.observed = observed
+}
+
+// expired reports whether a lease was not renewed for its duration since it
+// was observed to change
+func (s *Sharder) expired(lease *coordination.Lease, now time.Time) bool {
+	s.mu.Lock()
+	seen, ok := s.observed[lease.Name]
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	duration := s.opts.LeaseDuration
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return !now.Before(seen.at.Add(duration))
+}
+
+func (s *Sharder) leaseSpec(now time.Time) coordination.LeaseSpec {
+	return coordination.LeaseSpec{
+		HolderIdentity:       ptr.To(s.opts.Identity),
+		LeaseDurationSeconds: ptr.To(int32(s.opts.LeaseDuration / time.Second)),
+		RenewTime:            &metav1.MicroTime{Time: now},
+	}
+}
+
+func (s *Sharder) shardLeaseName(shard int) string {
+	return fmt.Sprintf("%s-shard-%d", s.opts.Name, shard)
+}
+
+func (s *Sharder) memberLeaseName(identity string) string {
+	return s.opts.Name + "-member-" + identity
+}
+
+func holder(lease *coordination.Lease) string {
+	return ptr.Deref(lease.Spec.HolderIdentity, "")
+}
+
+// owner returns the member a shard is assigned to. With rendezvous hashing,
+// only the shards of a replica that joins or leaves are reassigned.
+func owner(shard int, members []string) string {
+	var best string
+	var bestScore uint64
+	for _, member := range members {
+		sum := sha256.Sum256([]byte(member + "/" + strconv.Itoa(shard)))
+		if score := binary.BigEndian.Uint64(sum[:8]); best == "" || score > bestScore || (score == bestScore && member < best) {
+			best, bestScore = member, score
+		}
+	}
+	return best
+}
+
+// InClusterNamespace returns the namespace of the pod the controller runs in
+func InClusterNamespace() (string, error) {
+	namespace, err := os.ReadFile(inClusterNamespacePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the namespace of the controller: %w", err)
+	}
+	return strings.TrimSpace(string(namespace)), nil
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordination "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/cert-manager/aws-privateca-issuer/pkg/metrics"
+)
+
+const testShards = 8
+
+// testClock is shared by the replicas of a test
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Step(d time.Duration) { c.now = c.now.Add(d) }
+
+func newReplica(t *testing.T, c client.Client, clock *testClock, identity string) *Sharder {
+	s := New(c, logrtesting.NewTestLogger(t), Options{
+		Name:          "awspca",
+		Namespace:     "cert-manager",
+		Identity:      identity,
+		Shards:        testShards,
+		LeaseDuration: 15 * time.Second,
+		RenewPeriod:   5 * time.Second,
+	})
+	s.now = clock.Now
+	return s
+}
+
+// keys returns a CertificateRequest key for every shard
+func keys() []types.NamespacedName {
+	found := make(map[int]types.NamespacedName)
+	for i := 0; len(found) < testShards; i++ {
+		key := types.NamespacedName{Namespace: "ns1", Name: fmt.Sprintf("cr%d", i)}
+		found[Shard(key, testShards)] = key
+	}
+	keys := make([]types.NamespacedName, 0, testShards)
+	for _, key := range found {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// owners returns the replicas owning each key, failing if a key is owned
+// by more than one replica
+func owners(t *testing.T, replicas ...*Sharder) map[types.NamespacedName]string {
+	owners := make(map[types.NamespacedName]string)
+	for _, key := range keys() {
+		for _, replica := range replicas {
+			if replica.Owns(key) {
+				require.Empty(t, owners[key], "%s owned by both %s and %s", key, owners[key], replica.opts.Identity)
+				owners[key] = replica.opts.Identity
+			}
+		}
+	}
+	return owners
+}
+
+func TestShardingSingleReplica(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	clock := &testClock{now: time.Now()}
+	a := newReplica(t, c, clock, "a")
+
+	a.sync(context.TODO())
+	assert.Len(t, owners(t, a), testShards)
+	assert.Len(t, a.Gained(), testShards)
+
+	event := <-a.Gained()
+	shard, ok := ShardOf(event.Object)
+	assert.True(t, ok)
+	assert.Equal(t, fmt.Sprintf("awspca-shard-%d", shard), event.Object.GetName())
+
+	// Renewing does not report the shards as gained again
+	clock.Step(5 * time.Second)
+	a.sync(context.TODO())
+	assert.Len(t, owners(t, a), testShards)
+	assert.Len(t, a.Gained(), testShards-1)
+
+	// The claims lapse when the leases cannot be renewed
+	clock.Step(15 * time.Second)
+	assert.Empty(t, owners(t, a))
+}
+
+func TestShardsOwnedMetric(t *testing.T) {
+	failRenewals := false
+	c := fake.NewClientBuilder().
+		WithScheme(clientgoscheme.Scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if failRenewals {
+					return errors.New("etcdserver: request timed out")
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	clock := &testClock{now: time.Now()}
+	a := newReplica(t, c, clock, "a")
+
+	a.sync(context.TODO())
+	assert.Equal(t, float64(testShards), testutil.ToFloat64(metrics.ShardsOwned))
+
+	// Lapsed claims are no longer counted, even though they are still known
+	failRenewals = true
+	clock.Step(20 * time.Second)
+	a.sync(context.TODO())
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ShardsOwned))
+}
+
+func TestShardingHandover(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	clock := &testClock{now: time.Now()}
+	a := newReplica(t, c, clock, "a")
+	b := newReplica(t, c, clock, "b")
+
+	a.sync(context.TODO())
+	// b joins, but waits for a to release the shards assigned to b
+	b.sync(context.TODO())
+	assert.Len(t, owners(t, a, b), testShards)
+	assert.Zero(t, len(b.Gained()))
+
+	for range 2 {
+		clock.Step(5 * time.Second)
+		a.sync(context.TODO())
+		b.sync(context.TODO())
+		owners(t, a, b)
+	}
+	shards := owners(t, a, b)
+	assert.Len(t, shards, testShards)
+	assert.Contains(t, values(shards), "a")
+	assert.Contains(t, values(shards), "b")
+	assert.NotZero(t, len(b.Gained()))
+
+	// b is lost, a takes over its shards once its leases expire
+	for range 4 {
+		clock.Step(5 * time.Second)
+		a.sync(context.TODO())
+		owners(t, a, b)
+	}
+	shards = owners(t, a, b)
+	assert.Len(t, shards, testShards)
+	assert.NotContains(t, values(shards), "b")
+
+	// The member lease of b is removed
+	lease := new(coordination.Lease)
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: "cert-manager", Name: "awspca-member-b"}, lease)
+	assert.True(t, client.IgnoreNotFound(err) == nil && err != nil, "member lease of b deleted")
+}
+
+func TestShardingStop(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	clock := &testClock{now: time.Now()}
+	a := newReplica(t, c, clock, "a")
+	b := newReplica(t, c, clock, "b")
+
+	a.sync(context.TODO())
+	b.sync(context.TODO())
+	a.stop(context.TODO())
+
+	// b takes over right away, without waiting for the leases to expire
+	clock.Step(time.Second)
+	b.sync(context.TODO())
+	shards := owners(t, a, b)
+	assert.Len(t, shards, testShards)
+	assert.NotContains(t, values(shards), "a")
+}
+
+func TestOwner(t *testing.T) {
+	members := []string{"a", "b", "c"}
+	counts := make(map[string]int)
+	for shard := 0; shard < 64; shard++ {
+		counts[owner(shard, members)]++
+
+		// Only the shards of a replica that leaves are reassigned
+		if before := owner(shard, members); before != "c" {
+			assert.Equal(t, before, owner(shard, []string{"b", "a"}))
+		}
+	}
+	assert.Len(t, counts, len(members))
+}
+
+func values(m map[types.NamespacedName]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
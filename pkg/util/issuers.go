@@ -18,6 +18,8 @@ package util
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
 	"github.com/go-logr/logr"
@@ -29,20 +31,32 @@ import (
 
 var realtimeClock clock.Clock = clock.RealClock{}
 
-// GetIssuer returns either an AWSPCAClusterIssuer or AWSPCAIssuer by its name
-func GetIssuer(ctx context.Context, client client.Client, name types.NamespacedName) (api.GenericIssuer, error) {
-	iss := new(api.AWSPCAIssuer)
-	err := client.Get(ctx, name, iss)
-	if err != nil {
-		ciss := new(api.AWSPCAClusterIssuer)
-		cname := types.NamespacedName{
-			Name: name.Name,
-		}
-		err = client.Get(ctx, cname, ciss)
-		if err != nil {
-			return nil, err
-		}
-		return ciss, nil
+// Kinds of the issuers a CertificateRequest can refer to
+const (
+	IssuerKind        = "AWSPCAIssuer"
+	ClusterIssuerKind = "AWSPCAClusterIssuer"
+)
+
+// ErrUnknownIssuerKind is returned for an issuer reference of a kind that is
+// neither IssuerKind nor ClusterIssuerKind
+var ErrUnknownIssuerKind = errors.New("unknown issuer kind")
+
+// GetIssuer returns the AWSPCAIssuer or AWSPCAClusterIssuer of the given kind
+// by its name. The namespace of name is ignored for cluster issuers.
+func GetIssuer(ctx context.Context, client client.Client, kind string, name types.NamespacedName) (api.GenericIssuer, error) {
+	var iss api.GenericIssuer
+	switch kind {
+	case IssuerKind:
+		iss = new(api.AWSPCAIssuer)
+	case ClusterIssuerKind:
+		iss = new(api.AWSPCAClusterIssuer)
+		name.Namespace = ""
+	default:
+		return nil, fmt.Errorf("%w %q, must be %s or %s", ErrUnknownIssuerKind, kind, IssuerKind, ClusterIssuerKind)
+	}
+
+	if err := client.Get(ctx, name, iss); err != nil {
+		return nil, err
 	}
 	return iss, nil
 }
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestGetIssuer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, api.AddToScheme(scheme))
+
+	objects := []client.Object{
+		&api.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "issuer1"}},
+		&api.AWSPCAClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer1"}},
+		&api.AWSPCAClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "clusterissuer1"}},
+	}
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: api.GroupVersion.Group, Resource: "awspcaissuers"}, "issuer1", nil)
+
+	type testCase struct {
+		kind          string
+		name          types.NamespacedName
+		getErr        error
+		expectCluster bool
+		expectError   func(error) bool
+	}
+
+	tests := map[string]testCase{
+		"issuer": {
+			kind: IssuerKind,
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+		},
+		"cluster-issuer": {
+			kind:          ClusterIssuerKind,
+			name:          types.NamespacedName{Namespace: "ns1", Name: "clusterissuer1"},
+			expectCluster: true,
+		},
+		"issuer-does-not-fall-back-to-cluster-issuer": {
+			kind:        IssuerKind,
+			name:        types.NamespacedName{Namespace: "ns1", Name: "clusterissuer1"},
+			expectError: apierrors.IsNotFound,
+		},
+		"issuer-in-other-namespace": {
+			kind:        IssuerKind,
+			name:        types.NamespacedName{Namespace: "ns2", Name: "issuer1"},
+			expectError: apierrors.IsNotFound,
+		},
+		"api-error": {
+			kind:        IssuerKind,
+			name:        types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			getErr:      forbidden,
+			expectError: apierrors.IsForbidden,
+		},
+		"unknown-kind": {
+			kind:        "ClusterIssuer",
+			name:        types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			expectError: func(err error) bool { return assert.ErrorIs(t, err, ErrUnknownIssuerKind) },
+		},
+		"empty-kind": {
+			name:        types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			expectError: func(err error) bool { return assert.ErrorIs(t, err, ErrUnknownIssuerKind) },
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objects...).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if tc.getErr != nil {
+							return tc.getErr
+						}
+						return c.Get(ctx, key, obj, opts...)
+					},
+				}).
+				Build()
+
+			iss, err := GetIssuer(context.TODO(), c, tc.kind, tc.name)
+			if tc.expectError != nil {
+				assert.True(t, tc.expectError(err), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.name.Name, iss.GetName())
+			if tc.expectCluster {
+				assert.IsType(t, &api.AWSPCAClusterIssuer{}, iss)
+			} else {
+				assert.IsType(t, &api.AWSPCAIssuer{}, iss)
+			}
+		})
+	}
+}